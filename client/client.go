@@ -0,0 +1,297 @@
+// Package client is a typed client of the breeds API
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Breed struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Species       string  `json:"species"`
+	AverageWeight float64 `json:"average_weight"`
+}
+
+// BreedPatch holds the fields Patch changes, nil fields are kept
+type BreedPatch struct {
+	Name          *string  `json:"name,omitempty"`
+	Species       *string  `json:"species,omitempty"`
+	AverageWeight *float64 `json:"average_weight,omitempty"`
+}
+
+// SearchFilter narrows Search, zero fields do not filter
+type SearchFilter struct {
+	Species string
+	// MaxWeight keeps the breeds whose average weight is at most MaxWeight
+	MaxWeight *float64
+}
+
+// BreedsClient calls the breeds routes of the API
+type BreedsClient struct {
+	// BaseURL is the root of the API, e.g. http://127.0.0.1:5000/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// Header is added to every request, e.g. X-API-Key or Authorization
+	Header http.Header
+	// MaxRetries is the number of times GET, PUT and DELETE are retried after a network error, a 429, 502, 503 or 504
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each one
+	RetryBackoff time.Duration
+	// PageSize is the number of breeds List fetches per request
+	PageSize int
+}
+
+// New returns a BreedsClient for the API at baseURL
+func New(baseURL string) *BreedsClient {
+	return &BreedsClient{
+		BaseURL:      baseURL,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		Header:       http.Header{},
+		MaxRetries:   3,
+		RetryBackoff: 200 * time.Millisecond,
+		PageSize:     100,
+	}
+}
+
+// List returns an iterator over all the breeds by id, fetched a page at a time
+func (c *BreedsClient) List(ctx context.Context) *BreedIterator {
+	return &BreedIterator{ctx: ctx, client: c}
+}
+
+// Get returns the breed id, an error matching ErrNotFound when there is none
+func (c *BreedsClient) Get(ctx context.Context, id int) (Breed, error) {
+	var breed Breed
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/breeds/%d", id), nil, "", &breed)
+	return breed, err
+}
+
+// Search returns the breeds matching filter
+func (c *BreedsClient) Search(ctx context.Context, filter SearchFilter) ([]Breed, error) {
+	query := url.Values{}
+	if filter.Species != "" {
+		query.Set("species", filter.Species)
+	}
+	if filter.MaxWeight != nil {
+		query.Set("weight", strconv.FormatFloat(*filter.MaxWeight, 'f', -1, 64))
+	}
+	var breeds []Breed
+	err := c.do(ctx, http.MethodGet, "/breeds/search?"+query.Encode(), nil, "", &breeds)
+	return breeds, err
+}
+
+// Create creates breed, its ID is ignored, and returns it with the ID it was given
+func (c *BreedsClient) Create(ctx context.Context, breed Breed) (Breed, error) {
+	var created Breed
+	err := c.doJSON(ctx, http.MethodPost, "/breeds", breed, "application/json", &created)
+	return created, err
+}
+
+// Update replaces the breed breed.ID
+func (c *BreedsClient) Update(ctx context.Context, breed Breed) error {
+	return c.doJSON(ctx, http.MethodPut, fmt.Sprintf("/breeds/%d", breed.ID), breed, "application/json", nil)
+}
+
+// Patch changes the fields of the breed id set in patch and returns the breed
+func (c *BreedsClient) Patch(ctx context.Context, id int, patch BreedPatch) (Breed, error) {
+	var breed Breed
+	err := c.doJSON(ctx, http.MethodPatch, fmt.Sprintf("/breeds/%d", id), patch, "application/merge-patch+json", &breed)
+	return breed, err
+}
+
+// Delete deletes the breed id
+func (c *BreedsClient) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/breeds/%d", id), nil, "", nil)
+}
+
+// Import creates the breeds of csv, in the breeds.csv format, and returns how many were imported
+func (c *BreedsClient) Import(ctx context.Context, csv io.Reader) (int, error) {
+	body, err := io.ReadAll(csv)
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		Imported int `json:"imported"`
+	}
+	err = c.do(ctx, http.MethodPost, "/breeds/import", body, "text/csv", &result)
+	return result.Imported, err
+}
+
+// BreedIterator walks through the breeds returned by List
+//
+//	it := c.List(ctx)
+//	for it.Next() {
+//		breed := it.Breed()
+//	}
+//	if err := it.Err(); err != nil {
+type BreedIterator struct {
+	ctx     context.Context
+	client  *BreedsClient
+	afterID int
+	page    []Breed
+	current Breed
+	last    bool
+	err     error
+}
+
+// Next moves to the next breed, fetching the next page when needed, it returns false at the end or on error
+func (it *BreedIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.last {
+			return false
+		}
+		it.page, it.err = it.client.page(it.ctx, it.afterID)
+		if it.err != nil || len(it.page) == 0 {
+			return false
+		}
+		it.last = len(it.page) < it.client.pageSize()
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	it.afterID = it.current.ID
+	return true
+}
+
+// Breed returns the breed Next moved to
+func (it *BreedIterator) Breed() Breed {
+	return it.current
+}
+
+// Err returns the error which stopped Next, if any
+func (it *BreedIterator) Err() error {
+	return it.err
+}
+
+func (c *BreedsClient) pageSize() int {
+	if c.PageSize <= 0 {
+		return 100
+	}
+	return c.PageSize
+}
+
+func (c *BreedsClient) page(ctx context.Context, afterID int) ([]Breed, error) {
+	query := url.Values{"limit": {strconv.Itoa(c.pageSize())}}
+	if afterID > 0 {
+		query.Set("after_id", strconv.Itoa(afterID))
+	}
+	var breeds []Breed
+	err := c.do(ctx, http.MethodGet, "/breeds?"+query.Encode(), nil, "", &breeds)
+	return breeds, err
+}
+
+func (c *BreedsClient) doJSON(ctx context.Context, method, path string, in interface{}, contentType string, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, body, contentType, out)
+}
+
+// do sends the request, retrying the idempotent ones, and decodes the JSON answer into out when not nil
+func (c *BreedsClient) do(ctx context.Context, method, path string, body []byte, contentType string, out interface{}) error {
+	retries := 0
+	if method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete {
+		retries = c.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body, contentType)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("breeds API: invalid response to %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			problem := readProblem(resp)
+			resp.Body.Close()
+			if !retryable(resp.StatusCode) {
+				return problem
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			err = problem
+		} else if ctx.Err() != nil {
+			return err
+		}
+		if attempt >= retries {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *BreedsClient) send(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// backoff doubles RetryBackoff for each attempt and waits between half and all of it, for clients not to retry in step
+func (c *BreedsClient) backoff(attempt int) time.Duration {
+	delay := c.RetryBackoff << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header in seconds, the API does not send dates
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	"github.com/Asto-42/TechTestJaphy/internal"
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+var fixtureBreeds = []database_actions.BreedRecord{
+	{Species: "dog", PetSize: "small", Name: "affenpinscher", WeightMin: 6000, WeightMax: 5000},
+	{Species: "dog", PetSize: "large", Name: "akita", WeightMin: 45000, WeightMax: 35000},
+	{Species: "cat", PetSize: "medium", Name: "abyssinian", WeightMin: 4000, WeightMax: 3000},
+}
+
+// newTestClient returns a client of an in-process API serving fixtureBreeds, requests checked against the spec
+func newTestClient(t *testing.T) *BreedsClient {
+	t.Helper()
+	app := internal.NewApp(charmLog.New(io.Discard))
+	app.Store = internal.NewMemoryBreedStore(fixtureBreeds)
+	validator, err := internal.NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Validator = validator
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return New(server.URL + "/v1")
+}
+
+func TestListPages(t *testing.T) {
+	c := newTestClient(t)
+	c.PageSize = 2
+
+	var names []string
+	it := c.List(context.Background())
+	for it.Next() {
+		names = append(names, it.Breed().Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names, ","); got != "affenpinscher,akita,abyssinian" {
+		t.Errorf("names = %s", got)
+	}
+}
+
+func TestBreedsClient(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	created, err := c.Create(ctx, Breed{Name: "beagle", Species: "dog", AverageWeight: 12000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Name != "beagle" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	created.AverageWeight = 13000
+	if err := c.Update(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	name := "beagle harrier"
+	patched, err := c.Patch(ctx, created.ID, BreedPatch{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Name != name || patched.AverageWeight != 13000 {
+		t.Errorf("patched = %+v", patched)
+	}
+
+	got, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != patched {
+		t.Errorf("got %+v, want %+v", got, patched)
+	}
+
+	weight := 6000.0
+	breeds, err := c.Search(ctx, SearchFilter{Species: "dog", MaxWeight: &weight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breeds) != 1 || breeds[0].Name != "affenpinscher" {
+		t.Errorf("search = %+v", breeds)
+	}
+
+	imported, err := c.Import(ctx, strings.NewReader("id,species,pet_size,name,male,female\n1,cat,small,singapura,3000,2000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 1 {
+		t.Errorf("imported = %d", imported)
+	}
+
+	if err := c.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestProblemErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	_, err := c.Create(ctx, Breed{Species: "dog", AverageWeight: 12000})
+	var problem *ProblemError
+	if !errors.As(err, &problem) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("err = %v, want an invalid request problem", err)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Detail == "" {
+		t.Errorf("problem = %+v", problem)
+	}
+
+	_, err = c.Patch(ctx, 42, BreedPatch{})
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("empty patch: err = %v, want ErrInvalid", err)
+	}
+	name := "akita inu"
+	_, err = c.Patch(ctx, 42, BreedPatch{Name: &name})
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown breed: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		wantCalls int32
+		wantErr   error
+	}{
+		{name: "GET unavailable", method: http.MethodGet, status: http.StatusServiceUnavailable, wantCalls: 3, wantErr: ErrUnavailable},
+		{name: "GET rate limited", method: http.MethodGet, status: http.StatusTooManyRequests, wantCalls: 3, wantErr: ErrRateLimited},
+		{name: "DELETE timeout", method: http.MethodDelete, status: http.StatusGatewayTimeout, wantCalls: 3, wantErr: ErrTimeout},
+		{name: "POST not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, wantCalls: 1, wantErr: ErrUnavailable},
+		{name: "GET not found not retried", method: http.MethodGet, status: http.StatusNotFound, wantCalls: 1, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "try again", tt.status)
+			}))
+			defer server.Close()
+			c := New(server.URL)
+			c.MaxRetries = 2
+			c.RetryBackoff = 0
+
+			var err error
+			switch tt.method {
+			case http.MethodGet:
+				_, err = c.Get(context.Background(), 1)
+			case http.MethodDelete:
+				err = c.Delete(context.Background(), 1)
+			case http.MethodPost:
+				_, err = c.Create(context.Background(), Breed{Name: "beagle"})
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			var problem *ProblemError
+			if errors.As(err, &problem) && problem.Detail != "try again" {
+				t.Errorf("detail = %q, want the plain text body", problem.Detail)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(server.URL)
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		defer cancel()
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	_, err := c.Get(ctx, 1)
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want the problem and context.Canceled", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
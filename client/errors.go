@@ -0,0 +1,73 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The errors a ProblemError matches with errors.Is, after its status
+var (
+	ErrInvalid      = errors.New("invalid request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("service unavailable")
+	ErrTimeout      = errors.New("timeout")
+)
+
+// ProblemError is an error answer of the API, read from its RFC 9457 problem details body when it has one
+type ProblemError struct {
+	Status int
+	Type   string
+	Title  string
+	Detail string
+}
+
+func (e *ProblemError) Error() string {
+	title := e.Title
+	if title == "" {
+		title = http.StatusText(e.Status)
+	}
+	if e.Detail == "" {
+		return fmt.Sprintf("breeds API: %d %s", e.Status, title)
+	}
+	return fmt.Sprintf("breeds API: %d %s: %s", e.Status, title, e.Detail)
+}
+
+// Is maps the status of the problem to the sentinel errors of the package
+func (e *ProblemError) Is(target error) bool {
+	switch e.Status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == ErrInvalid
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	case http.StatusGatewayTimeout:
+		return target == ErrTimeout
+	}
+	return false
+}
+
+// readProblem reads the error answer resp, a plain text answer, from a proxy or an unknown route, is kept as Detail
+func readProblem(resp *http.Response) *ProblemError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	problem := &ProblemError{Status: resp.StatusCode}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") && json.Unmarshal(body, problem) == nil {
+		problem.Status = resp.StatusCode
+		return problem
+	}
+	problem.Detail = strings.TrimSpace(string(body))
+	return problem
+}
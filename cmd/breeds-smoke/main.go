@@ -0,0 +1,81 @@
+// breeds-smoke runs the breeds API contract checks against any deployment
+//
+//	breeds-smoke -url https://staging.example.com -header "Authorization: Bearer $TOKEN" -junit smoke.xml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/tests"
+)
+
+// headerFlags collects repeated `-header "Name: value"` flags
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q must be formatted as 'Name: value'", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	var headers headerFlags
+	baseURL := flag.String("url", "http://127.0.0.1:50010", "base URL of the deployment")
+	flag.Var(&headers, "header", "header added to every request, as 'Name: value' (repeatable)")
+	csvFile := flag.String("csv", "", "compare GET /v1/breeds with this breeds CSV file (skipped when empty)")
+	readOnly := flag.Bool("read-only", false, "only run non-destructive checks")
+	junitFile := flag.String("junit", "", "write a JUnit XML report to this file")
+	jsonFile := flag.String("json", "", "write a JSON report to this file")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of each HTTP request")
+	flag.Parse()
+
+	target := strings.TrimSuffix(*baseURL, "/")
+	checker := tests.NewChecker(target + "/v1")
+	checker.API.HTTPClient.Timeout = *timeout
+	checker.Logf = func(format string, args ...interface{}) {
+		fmt.Printf(format+"\n", args...)
+	}
+	for _, header := range headers {
+		name, value, _ := strings.Cut(header, ":")
+		checker.API.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	report := &Report{Target: target, Timestamp: time.Now()}
+	report.Run("health", func() error { return checker.WaitForServer(target) })
+	if *csvFile != "" {
+		report.Run("breeds match CSV", func() error { return checker.CheckBreedsMatchCSV(*csvFile) })
+	}
+	report.Run("read-only", checker.CheckReadOnly)
+	if !*readOnly {
+		report.Run("CRUD round-trip", checker.CheckCRUD)
+		report.Run("cleanup", checker.Cleanup)
+	}
+
+	if *junitFile != "" {
+		if err := report.WriteJUnit(*junitFile); err != nil {
+			fmt.Printf("❌ Erreur lors de l'écriture du rapport JUnit : %s\n", err)
+			os.Exit(2)
+		}
+	}
+	if *jsonFile != "" {
+		if err := report.WriteJSON(*jsonFile); err != nil {
+			fmt.Printf("❌ Erreur lors de l'écriture du rapport JSON : %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	fmt.Printf("=== %d/%d vérifications réussies sur %s ===\n", len(report.Cases)-report.Failures(), len(report.Cases), target)
+	if report.Failures() > 0 {
+		os.Exit(1)
+	}
+}
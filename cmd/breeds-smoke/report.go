@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Report records the outcome of every check run against Target
+type Report struct {
+	Target    string       `json:"target"`
+	Timestamp time.Time    `json:"timestamp"`
+	Cases     []CaseResult `json:"cases"`
+}
+
+type CaseResult struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Run times check and records its result
+func (r *Report) Run(name string, check func() error) {
+	start := time.Now()
+	err := check()
+	result := CaseResult{Name: name, Passed: err == nil, Duration: time.Since(start)}
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Printf("❌ %s : %s\n", name, err)
+	}
+	r.Cases = append(r.Cases, result)
+}
+
+func (r *Report) Failures() int {
+	failures := 0
+	for _, c := range r.Cases {
+		if !c.Passed {
+			failures++
+		}
+	}
+	return failures
+}
+
+func (r *Report) WriteJSON(path string) error {
+	body, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, body, 0o644)
+}
+
+type junitSuite struct {
+	XMLName   xml.Name    `xml:"testsuite"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func (r *Report) WriteJUnit(path string) error {
+	suite := junitSuite{
+		Name:      "breeds-smoke " + r.Target,
+		Tests:     len(r.Cases),
+		Failures:  r.Failures(),
+		Timestamp: r.Timestamp.Format(time.RFC3339),
+	}
+	for _, c := range r.Cases {
+		tc := junitCase{Name: c.Name, ClassName: "breeds-smoke", Time: c.Duration.Seconds()}
+		if !c.Passed {
+			tc.Failure = &junitFailure{Message: c.Error, Body: c.Error}
+		}
+		suite.Time += tc.Time
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	body, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), body...), 0o644)
+}
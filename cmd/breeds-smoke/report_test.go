@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReportWriters(t *testing.T) {
+	report := &Report{Target: "http://api", Timestamp: time.Now()}
+	report.Run("passing", func() error { return nil })
+	report.Run("failing", func() error { return errors.New("boom") })
+
+	if report.Failures() != 1 {
+		t.Fatalf("Failures() = %d, want 1", report.Failures())
+	}
+
+	dir := t.TempDir()
+	junitPath := filepath.Join(dir, "smoke.xml")
+	if err := report.WriteJUnit(junitPath); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := os.ReadFile(junitPath)
+	var suite junitSuite
+	if err := xml.Unmarshal(body, &suite); err != nil {
+		t.Fatal(err)
+	}
+	if suite.Tests != 2 || suite.Failures != 1 || suite.Cases[1].Failure == nil || suite.Cases[1].Failure.Message != "boom" {
+		t.Errorf("unexpected JUnit suite: %+v", suite)
+	}
+
+	jsonPath := filepath.Join(dir, "smoke.json")
+	if err := report.WriteJSON(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	body, _ = os.ReadFile(jsonPath)
+	var decoded Report
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Cases) != 2 || decoded.Cases[1].Error != "boom" {
+		t.Errorf("unexpected JSON report: %+v", decoded)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	"github.com/Asto-42/TechTestJaphy/internal"
+	charmLog "github.com/charmbracelet/log"
+)
+
+// appEnv is APP_ENV, "production" by default, which sets the defaults of the settings differing per environment
+//
+// The development defaults are opted into with APP_ENV=development, a deployment missing the variable stays strict
+func appEnv() string {
+	return envOr("APP_ENV", "production")
+}
+
+// logFormatter is JSON with LOG_FORMAT=json, text otherwise
+func logFormatter() charmLog.Formatter {
+	if os.Getenv("LOG_FORMAT") == "json" {
+		return charmLog.JSONFormatter
+	}
+	return charmLog.TextFormatter
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envList reads a comma-separated list
+func envList(key, fallback string) []string {
+	var values []string
+	for _, value := range strings.Split(envOr(key, fallback), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func envInt(logger *charmLog.Logger, key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("%s: %s", key, err.Error()))
+	}
+	return n
+}
+
+func envDuration(logger *charmLog.Logger, key, fallback string) time.Duration {
+	d, err := time.ParseDuration(envOr(key, fallback))
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("%s: %s", key, err.Error()))
+	}
+	return d
+}
+
+// corsConfigFromEnv reads the CORS_* settings, in development the local frontend dev servers are allowed by default
+func corsConfigFromEnv(logger *charmLog.Logger) internal.CORSConfig {
+	origins := ""
+	if appEnv() == "development" {
+		origins = "http://localhost:3000,http://localhost:5173"
+	}
+
+	return internal.CORSConfig{
+		AllowedOrigins:   envList("CORS_ALLOWED_ORIGINS", origins),
+		AllowedMethods:   envList("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE"),
+		AllowedHeaders:   envList("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-API-Key,X-Request-ID,traceparent,tracestate"),
+		ExposedHeaders:   envList("CORS_EXPOSED_HEADERS", "RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After,X-Request-ID"),
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
+		MaxAge:           envDuration(logger, "CORS_MAX_AGE", "10m"),
+	}
+}
+
+// securityConfigFromEnv enables HSTS for a year outside development, HSTS_MAX_AGE=0s disables it
+func securityConfigFromEnv(logger *charmLog.Logger) internal.SecurityConfig {
+	hsts := "8760h"
+	if appEnv() == "development" {
+		hsts = "0s"
+	}
+
+	return internal.SecurityConfig{
+		ContentSecurityPolicy: envOr("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		HSTSMaxAge:            envDuration(logger, "HSTS_MAX_AGE", hsts),
+	}
+}
+
+// rateLimiterFromEnv reads RATE_LIMIT_READ, RATE_LIMIT_WRITE and RATE_LIMIT_AUTH_FAILURES, rate limiting is disabled
+// with RATE_LIMIT_DISABLED=true
+func rateLimiterFromEnv(logger *charmLog.Logger) *internal.RateLimiter {
+	if os.Getenv("RATE_LIMIT_DISABLED") == "true" {
+		logger.Warn("Rate limiting is disabled")
+		return nil
+	}
+
+	limiter := &internal.RateLimiter{Store: internal.NewMemoryLimiterStore()}
+	for _, limit := range []struct {
+		env      string
+		fallback string
+		dest     *internal.RateLimit
+	}{
+		{"RATE_LIMIT_READ", "600/m", &limiter.Read},
+		{"RATE_LIMIT_WRITE", "60/m", &limiter.Write},
+		{"RATE_LIMIT_AUTH_FAILURES", "10/m", &limiter.AuthFailures},
+	} {
+		value := os.Getenv(limit.env)
+		if value == "" {
+			value = limit.fallback
+		}
+		parsed, err := internal.ParseRateLimit(value)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("%s: %s", limit.env, err.Error()))
+		}
+		*limit.dest = parsed
+	}
+	return limiter
+}
+
+// queryTimeoutsFromEnv reads DB_QUERY_TIMEOUT, 5s by default, and the per-operation overrides of DB_QUERY_TIMEOUTS,
+// e.g. `breeds.search=10s,auth=1s`
+func queryTimeoutsFromEnv(logger *charmLog.Logger) internal.QueryTimeouts {
+	timeouts := internal.QueryTimeouts{
+		Default:      envDuration(logger, "DB_QUERY_TIMEOUT", "5s"),
+		PerOperation: map[string]time.Duration{},
+	}
+	for _, override := range envList("DB_QUERY_TIMEOUTS", "") {
+		operation, value, found := strings.Cut(override, "=")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !found || err != nil {
+			logger.Fatal(fmt.Sprintf("DB_QUERY_TIMEOUTS: invalid timeout %q, want operation=duration", override))
+		}
+		timeouts.PerOperation[strings.TrimSpace(operation)] = timeout
+	}
+	return timeouts
+}
+
+// sizeClassesFromEnv reads SIZE_CLASSES, whose species replace those of internal.DefaultSizeClasses
+func sizeClassesFromEnv(logger *charmLog.Logger) internal.SizeClasses {
+	overrides, err := internal.ParseSizeClasses(os.Getenv("SIZE_CLASSES"))
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("SIZE_CLASSES: %s", err.Error()))
+	}
+	classes := internal.SizeClasses{}
+	for species, list := range internal.DefaultSizeClasses {
+		classes[species] = list
+	}
+	for species, list := range overrides {
+		classes[species] = list
+	}
+	return classes
+}
+
+// poolConfigFromEnv reads the DB_MAX_* and DB_CONN_* settings of the shared connection pool
+//
+// The migrator holds up to two connections while it runs, the pool needs at least two
+func poolConfigFromEnv(logger *charmLog.Logger) database_actions.PoolConfig {
+	pool := database_actions.PoolConfig{
+		MaxOpenConns:    envInt(logger, "DB_MAX_OPEN_CONNS", 10),
+		MaxIdleConns:    envInt(logger, "DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: envDuration(logger, "DB_CONN_MAX_LIFETIME", "30m"),
+		ConnMaxIdleTime: envDuration(logger, "DB_CONN_MAX_IDLE_TIME", "5m"),
+	}
+	if pool.MaxOpenConns == 1 {
+		logger.Fatal("DB_MAX_OPEN_CONNS: the pool needs at least 2 connections")
+	}
+	return pool
+}
+
+// retryConfigFromEnv reads how long to wait for the database at startup, up to about a minute by default
+func retryConfigFromEnv(logger *charmLog.Logger) database_actions.RetryConfig {
+	return database_actions.RetryConfig{
+		Attempts:     envInt(logger, "DB_CONNECT_ATTEMPTS", 10),
+		InitialDelay: envDuration(logger, "DB_CONNECT_BACKOFF", "500ms"),
+		MaxDelay:     envDuration(logger, "DB_CONNECT_MAX_BACKOFF", "15s"),
+	}
+}
+
+// specValidatorFromEnv validates the requests against the OpenAPI spec, and the responses too, which buffers them,
+// only with an explicit APP_ENV=development or OPENAPI_VALIDATE_RESPONSES=true
+func specValidatorFromEnv(logger *charmLog.Logger) *internal.SpecValidator {
+	validator, err := internal.NewSpecValidator()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	development := os.Getenv("APP_ENV") == "development"
+	validator.ValidateResponses = envOr("OPENAPI_VALIDATE_RESPONSES", strconv.FormatBool(development)) == "true"
+	if validator.ValidateResponses {
+		logger.Info("Responses are validated against the OpenAPI spec")
+	}
+	return validator
+}
@@ -0,0 +1,34 @@
+package database_actions
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM breeds WHERE species = ? AND weight_min <= ?"
+	tests := []struct {
+		backend Backend
+		want    string
+	}{
+		{BackendMySQL, query},
+		{BackendSQLite, query},
+		{BackendPostgres, "SELECT id FROM breeds WHERE species = $1 AND weight_min <= $2"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.backend.Rebind(query); got != tt.want {
+			t.Errorf("%s.Rebind() = %q, want %q", tt.backend, got, tt.want)
+		}
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	tests := map[string]Backend{"": BackendMySQL, "MySQL": BackendMySQL, "sqlite3": BackendSQLite, "postgresql": BackendPostgres}
+	for name, want := range tests {
+		got, err := ParseBackend(name)
+		if err != nil || got != want {
+			t.Errorf("ParseBackend(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseBackend("oracle"); err == nil {
+		t.Error("ParseBackend(\"oracle\") should fail")
+	}
+}
@@ -1,53 +1,128 @@
 package database_actions
 
 import (
+	"context"
+	"database/sql"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
-	"database/sql"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func ImportBreeds(db *sql.DB, filePath string) error {
+// BreedRecord is a breed as described by a line of breeds.csv
+type BreedRecord struct {
+	Species   string
+	PetSize   string
+	Name      string
+	WeightMin float64
+	WeightMax float64
+}
+
+// ReadBreedsFile parses the breeds CSV file at filePath
+func ReadBreedsFile(filePath string) ([]BreedRecord, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("Cannot open file %s: %w", filePath, err)
+		return nil, fmt.Errorf("Cannot open file %s: %w", filePath, err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return ParseBreeds(file)
+}
+
+// ParseBreeds reads breeds from a CSV whose first line is a header
+func ParseBreeds(r io.Reader) ([]BreedRecord, error) {
+	reader := csv.NewReader(r)
 	reader.Comma = ','
 	records, err := reader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("Cannot read CSV file: %w", err)
+		return nil, fmt.Errorf("Cannot read CSV file: %w", err)
 	}
 
+	breeds := make([]BreedRecord, 0, len(records))
 	for i, row := range records {
 		if i == 0 {
 			continue
 		}
 		if len(row) != 6 {
-			return fmt.Errorf("invalid format line %d", i+1)
+			return nil, fmt.Errorf("invalid format line %d", i+1)
 		}
-		species := strings.TrimSpace(row[1])
-		petSize := strings.TrimSpace(row[2])
-		name := strings.TrimSpace(row[3])
 		weightMin, err := strconv.ParseFloat(strings.TrimSpace(row[4]), 64)
 		if err != nil {
-			return fmt.Errorf("invalid weight_min at line %d: %w", i+1, err)
+			return nil, fmt.Errorf("invalid weight_min at line %d: %w", i+1, err)
 		}
 		weightMax, err := strconv.ParseFloat(strings.TrimSpace(row[5]), 64)
 		if err != nil {
-			return fmt.Errorf("invalid weight_max at line %d: %w", i+1, err)
+			return nil, fmt.Errorf("invalid weight_max at line %d: %w", i+1, err)
+		}
+		breeds = append(breeds, BreedRecord{
+			Species:   strings.TrimSpace(row[1]),
+			PetSize:   strings.TrimSpace(row[2]),
+			Name:      strings.TrimSpace(row[3]),
+			WeightMin: weightMin,
+			WeightMax: weightMax,
+		})
+	}
+
+	return breeds, nil
+}
+
+// SizeClassifier returns the size class of a breed of species whose average weight is weight grams, false when the
+// species has no classes
+type SizeClassifier func(species string, weight float64) (string, bool)
+
+// ClassifyBreeds sets the size of the records from their average weight, those of species without classes keep the
+// size of the CSV
+func ClassifyBreeds(records []BreedRecord, classify SizeClassifier) []BreedRecord {
+	for i, record := range records {
+		if size, ok := classify(record.Species, (record.WeightMin+record.WeightMax)/2); ok {
+			records[i].PetSize = size
+		}
+	}
+	return records
+}
+
+// ImportStats counts the rows handled by ImportBreeds
+type ImportStats struct {
+	Imported int
+	Failed   int
+}
+
+// ImportBreeds inserts the breeds of the CSV file at filePath, sized by classify, it stops at the first row which fails
+//
+// The import is traced as one span, parent of the spans of its inserts
+func ImportBreeds(ctx context.Context, db *sql.DB, b Backend, filePath string, classify SizeClassifier) (stats ImportStats, err error) {
+	ctx, span := tracer.Start(ctx, "ImportBreeds", trace.WithAttributes(attribute.String("file", filePath)))
+	defer func() {
+		span.SetAttributes(attribute.Int("rows.imported", stats.Imported), attribute.Int("rows.failed", stats.Failed))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 		}
-		_, err = db.Exec(
-			"INSERT INTO breeds (species, pet_size, name, weight_min, weight_max) VALUES (?, ?, ?, ?, ?)",
-			species, petSize, name, weightMin, weightMax,
+		span.End()
+	}()
+
+	breeds, err := ReadBreedsFile(filePath)
+	if err != nil {
+		return stats, err
+	}
+	breeds = ClassifyBreeds(breeds, classify)
+
+	for i, breed := range breeds {
+		_, err = db.ExecContext(ctx, b.Rebind(
+			"INSERT INTO breeds (species, pet_size, name, weight_min, weight_max) VALUES (?, ?, ?, ?, ?)"),
+			breed.Species, breed.PetSize, breed.Name, breed.WeightMin, breed.WeightMax,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert record at line %d: %w", i+1, err)
+			stats.Failed++
+			return stats, fmt.Errorf("failed to insert record at line %d: %w", i+2, err)
 		}
+		stats.Imported++
 	}
-	return nil
+	return stats, nil
 }
@@ -0,0 +1,78 @@
+package database_actions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBreeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []BreedRecord
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			csv:  "id,species,pet_size,name,male,female\n1, dog ,small,affenpinscher,6000,5000\n",
+			want: []BreedRecord{{Species: "dog", PetSize: "small", Name: "affenpinscher", WeightMin: 6000, WeightMax: 5000}},
+		},
+		{name: "header only", csv: "id,species,pet_size,name,male,female\n", want: []BreedRecord{}},
+		{name: "missing column", csv: "h\n1,dog,small,affenpinscher,6000\n", wantErr: true},
+		{name: "invalid weight", csv: "id,species,pet_size,name,male,female\n1,dog,small,affenpinscher,six,5000\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBreeds(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyBreeds(t *testing.T) {
+	classify := func(species string, weight float64) (string, bool) {
+		if species != "dog" {
+			return "", false
+		}
+		if weight < 10000 {
+			return "small", true
+		}
+		return "large", true
+	}
+	records := []BreedRecord{
+		{Species: "dog", PetSize: "tall", Name: "beagle", WeightMin: 11000, WeightMax: 9000},
+		{Species: "dog", PetSize: "medium", Name: "pug", WeightMin: 8000, WeightMax: 7000},
+		{Species: "rabbit", PetSize: "tall", Name: "flemish giant", WeightMin: 7000, WeightMax: 7000},
+	}
+
+	got := ClassifyBreeds(records, classify)
+	for i, want := range []string{"large", "small", "tall"} {
+		if got[i].PetSize != want {
+			t.Errorf("%s: pet_size = %q, want %q", got[i].Name, got[i].PetSize, want)
+		}
+	}
+}
+
+func TestReadBreedsFile(t *testing.T) {
+	breeds, err := ReadBreedsFile("../breeds.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breeds) == 0 {
+		t.Fatal("no breeds read from breeds.csv")
+	}
+}
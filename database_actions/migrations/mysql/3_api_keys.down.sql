@@ -0,0 +1 @@
+DROP TABLE IF EXISTS core.api_keys;
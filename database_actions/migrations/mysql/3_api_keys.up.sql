@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS core.api_keys (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL
+);
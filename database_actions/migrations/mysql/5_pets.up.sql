@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS core.pets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    species VARCHAR(50) NOT NULL,
+    breed_id INT NULL,
+    mixed_breed BOOLEAN NOT NULL DEFAULT FALSE,
+    birth_date DATE NULL,
+    sex VARCHAR(10) NOT NULL,
+    neutered BOOLEAN NOT NULL DEFAULT FALSE,
+    current_weight INT NOT NULL,
+    activity_level VARCHAR(20) NOT NULL,
+    FOREIGN KEY (breed_id) REFERENCES core.breeds (id) ON DELETE SET NULL
+);
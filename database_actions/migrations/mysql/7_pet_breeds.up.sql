@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS core.pet_breeds (
+    pet_id INT NOT NULL,
+    breed_id INT NOT NULL,
+    share INT NOT NULL,
+    PRIMARY KEY (pet_id, breed_id),
+    FOREIGN KEY (pet_id) REFERENCES core.pets (id) ON DELETE CASCADE,
+    FOREIGN KEY (breed_id) REFERENCES core.breeds (id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS core.growth_curves (
+    species VARCHAR(50) NOT NULL,
+    pet_size VARCHAR(50) NOT NULL,
+    birth_share DOUBLE NOT NULL,
+    adult_weeks INT NOT NULL,
+    spread DOUBLE NOT NULL,
+    PRIMARY KEY (species, pet_size)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS core.breed_growth_curves;
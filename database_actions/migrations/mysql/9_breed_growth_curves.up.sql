@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS core.breed_growth_curves (
+    breed_id INT PRIMARY KEY,
+    birth_share DOUBLE NOT NULL,
+    adult_weeks INT NOT NULL,
+    spread DOUBLE NOT NULL,
+    FOREIGN KEY (breed_id) REFERENCES core.breeds (id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS role_assignments (
+    subject VARCHAR(255) NOT NULL PRIMARY KEY,
+    role VARCHAR(20) NOT NULL,
+    assigned_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS breed_growth_curves (
+    breed_id INTEGER PRIMARY KEY REFERENCES breeds (id) ON DELETE CASCADE,
+    birth_share DOUBLE PRECISION NOT NULL,
+    adult_weeks INTEGER NOT NULL,
+    spread DOUBLE PRECISION NOT NULL
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS breeds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    species VARCHAR(50) NOT NULL,
+    pet_size VARCHAR(50) NOT NULL,
+    name VARCHAR(500) NOT NULL,
+    weight_min INTEGER NOT NULL,
+    weight_max INTEGER NOT NULL
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS pets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    species VARCHAR(50) NOT NULL,
+    breed_id INTEGER NULL REFERENCES breeds (id) ON DELETE SET NULL,
+    mixed_breed BOOLEAN NOT NULL DEFAULT FALSE,
+    birth_date DATE NULL,
+    sex VARCHAR(10) NOT NULL,
+    neutered BOOLEAN NOT NULL DEFAULT FALSE,
+    current_weight INTEGER NOT NULL,
+    activity_level VARCHAR(20) NOT NULL
+);
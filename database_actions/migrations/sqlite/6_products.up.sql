@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    kcal_per_kg INTEGER NOT NULL,
+    species VARCHAR(50) NOT NULL,
+    pet_sizes VARCHAR(255) NOT NULL DEFAULT '',
+    life_stages VARCHAR(255) NOT NULL DEFAULT ''
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pet_breeds;
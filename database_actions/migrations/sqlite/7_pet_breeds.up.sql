@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS pet_breeds (
+    pet_id INTEGER NOT NULL REFERENCES pets (id) ON DELETE CASCADE,
+    breed_id INTEGER NOT NULL REFERENCES breeds (id) ON DELETE CASCADE,
+    share INTEGER NOT NULL,
+    PRIMARY KEY (pet_id, breed_id)
+);
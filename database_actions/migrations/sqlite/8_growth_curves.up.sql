@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS growth_curves (
+    species VARCHAR(50) NOT NULL,
+    pet_size VARCHAR(50) NOT NULL,
+    birth_share REAL NOT NULL,
+    adult_weeks INTEGER NOT NULL,
+    spread REAL NOT NULL,
+    PRIMARY KEY (species, pet_size)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS breed_growth_curves;
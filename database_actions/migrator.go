@@ -1,50 +1,119 @@
 package database_actions
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-var driver database.Driver
+const (
+	migrationLockName = "core_schema_migrations"
+	// migrationLockKey is the PostgreSQL advisory lock id, derived from migrationLockName
+	migrationLockKey = 7235092168134519
+	// migrationLockTimeout is the number of seconds to wait for another instance to release the lock
+	migrationLockTimeout = 10
+)
 
-// InitMigrator initiates values essential for migrations
-func InitMigrator(dsnMigrate string) error {
-	var err error
-	db, err := sql.Open("mysql", dsnMigrate)
-	if err != nil {
-		return fmt.Errorf("error while opening db connection: %w", err)
-	}
-	driver, err = mysql.WithInstance(db, &mysql.Config{})
-	if err != nil {
-		return fmt.Errorf("error while instanciating migration driver: %w", err)
+// migrationsFS holds one directory of migrations per Backend, embedded so the binary runs from any directory
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+var (
+	// ErrMigrationLocked is returned when another instance currently holds the migration lock
+	ErrMigrationLocked = errors.New("migrations are locked by another instance")
+	// ErrMigratorNotInitialized is returned when InitMigrator was not called, e.g. with the memory store
+	ErrMigratorNotInitialized = errors.New("migrator is not initialized")
+)
+
+var (
+	migratorDB *sql.DB
+	backend    Backend
+	migrateMu  sync.Mutex
+)
+
+// MigrationStatus describes the state of the schema as seen by the migrator
+//
+// Version is nil when no migration has ever been applied
+type MigrationStatus struct {
+	Version *uint              `json:"version"`
+	Dirty   bool               `json:"dirty"`
+	Pending []PendingMigration `json:"pending"`
+}
+
+// PendingMigration is an up migration which has not been applied yet
+type PendingMigration struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	SQL        string `json:"sql"`
+}
+
+// InitMigrator sets the pool migrations run on, the one shared with the stores
+//
+// A nil db disables migrations, as when the breeds are served from memory.
+//
+// MySQL and PostgreSQL migrations hold up to two connections of the pool while they run
+func InitMigrator(db *sql.DB, b Backend) error {
+	switch b {
+	case BackendMySQL, BackendSQLite, BackendPostgres:
+	default:
+		return fmt.Errorf("unsupported backend %s", b)
 	}
+	migratorDB = db
+	backend = b
 
 	return nil
 }
 
 // RunMigrate performs all or only some up/down migrations
 //
-// Default 'steps' as 0 (runs all migrations)
+// Default 'steps' as 0 (runs all migrations). A negative 'steps' runs down migrations.
+// Only one instance can run migrations at a time, see ErrMigrationLocked.
 func RunMigrate(migrationType string, steps int) (string, error) {
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://database_actions/migrations",
-		"mysql",
-		driver,
-	)
+	var msg string
+	err := WithMigrationLock(func() error {
+		var err error
+		msg, err = runMigrate(migrationType, steps)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return msg, nil
+}
+
+func runMigrate(migrationType string, steps int) (string, error) {
+	m, release, err := newMigrate()
 	if err != nil {
 		return "", fmt.Errorf("error while instanciating new migration ("+migrationType+") with DB : %w", err)
 	}
+	defer release()
 
 	if steps != 0 {
-		m.Steps(steps)
+		err = m.Steps(steps)
+		if errors.Is(err, migrate.ErrNoChange) {
+			return "Migration(s) : " + migrate.ErrNoChange.Error(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("error while running %d %s migration step(s): %w", steps, migrationType, err)
+		}
 	} else {
 		if migrationType == "up" {
 			err = m.Up()
@@ -70,12 +139,202 @@ func RunMigrate(migrationType string, steps int) (string, error) {
 	return migrationsSuccessMessage(migrationType, steps), nil
 }
 
+// GetMigrationStatus returns the current schema version, its dirty flag and the migrations still to apply
+func GetMigrationStatus() (*MigrationStatus, error) {
+	m, release, err := newMigrate()
+	if err != nil {
+		return nil, fmt.Errorf("error while instanciating migrate: %w", err)
+	}
+	defer release()
+
+	status := &MigrationStatus{Pending: []PendingMigration{}}
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("error while reading migration version: %w", err)
+	}
+	if err == nil {
+		status.Version = &version
+		status.Dirty = dirty
+	}
+
+	src, err := migrationsSource(backend)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening migrations source: %w", err)
+	}
+	defer src.Close()
+
+	v, err := src.First()
+	for err == nil {
+		if status.Version == nil || v > *status.Version {
+			pending, readErr := readUpMigration(src, v)
+			if readErr != nil {
+				return nil, readErr
+			}
+			if pending != nil {
+				status.Pending = append(status.Pending, *pending)
+			}
+		}
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error while listing migrations: %w", err)
+	}
+
+	return status, nil
+}
+
+// readUpMigration returns nil when the version has no up migration
+func readUpMigration(src source.Driver, version uint) (*PendingMigration, error) {
+	r, identifier, err := src.ReadUp(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading migration %d: %w", version, err)
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading migration %d: %w", version, err)
+	}
+
+	return &PendingMigration{Version: version, Identifier: identifier, SQL: string(body)}, nil
+}
+
+// newMigrate returns a migrate instance on a connection of the shared pool, release gives the connection back
+//
+// migrate.Migrate.Close is never called, it would close the shared pool
+func newMigrate() (m *migrate.Migrate, release func(), err error) {
+	if migratorDB == nil {
+		return nil, nil, ErrMigratorNotInitialized
+	}
+
+	src, err := migrationsSource(backend)
+	if err != nil {
+		return nil, nil, err
+	}
+	driver, conn, err := migrationDriver(context.Background())
+	if err != nil {
+		src.Close()
+		return nil, nil, err
+	}
+	release = func() {
+		src.Close()
+		if conn != nil {
+			conn.Close()
+		}
+	}
+
+	m, err = migrate.NewWithInstance("iofs", src, string(backend), driver)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return m, release, nil
+}
+
+// migrationDriver returns the migrate database driver, along with the pool connection it holds for MySQL and PostgreSQL
+func migrationDriver(ctx context.Context) (database.Driver, *sql.Conn, error) {
+	if backend == BackendSQLite {
+		driver, err := sqlite.WithInstance(migratorDB, &sqlite.Config{})
+		return driver, nil, err
+	}
+
+	conn, err := migratorDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while acquiring migration connection: %w", err)
+	}
+	var driver database.Driver
+	if backend == BackendPostgres {
+		driver, err = postgres.WithConnection(ctx, conn, &postgres.Config{})
+	} else {
+		driver, err = mysql.WithConnection(ctx, conn, &mysql.Config{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("error while instanciating migration driver: %w", err)
+	}
+	return driver, conn, nil
+}
+
+func migrationsSource(b Backend) (source.Driver, error) {
+	return iofs.New(migrationsFS, "migrations/"+string(b))
+}
+
+// WithMigrationLock runs fn while holding an advisory lock shared by every instance of the API, no migration can run
+// meanwhile
+//
+// Callers of the same process are not waited for, they get ErrMigrationLocked right away. SQLite databases are local
+// files used by a single process, the in-process mutex is enough for them.
+func WithMigrationLock(fn func() error) error {
+	if migratorDB == nil {
+		return ErrMigratorNotInitialized
+	}
+	if !migrateMu.TryLock() {
+		return ErrMigrationLocked
+	}
+	defer migrateMu.Unlock()
+
+	if backend == BackendSQLite {
+		return fn()
+	}
+
+	ctx := context.Background()
+	conn, err := migratorDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error while acquiring migration lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	if backend == BackendPostgres {
+		err = acquirePostgresLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+		return fn()
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&acquired)
+	if err != nil {
+		return fmt.Errorf("error while acquiring migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return ErrMigrationLocked
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName)
+
+	return fn()
+}
+
+// acquirePostgresLock polls pg_try_advisory_lock, PostgreSQL having no lock wait timeout of its own
+func acquirePostgresLock(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(migrationLockTimeout * time.Second)
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey).Scan(&acquired)
+		if err != nil {
+			return fmt.Errorf("error while acquiring migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
 func migrationsSuccessMessage(migrationType string, steps int) string {
 	msg := "Successfully ran"
 	if steps == 0 {
 		return msg + " all " + migrationType + " migrations"
 	}
-	if steps == 1 {
+	if steps == 1 || steps == -1 {
 		return msg + " 1 " + migrationType + " migration"
 	}
 
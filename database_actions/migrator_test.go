@@ -0,0 +1,137 @@
+package database_actions
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithMigrationLock(t *testing.T) {
+	if err := InitMigrator(nil, BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err := WithMigrationLock(func() error { return nil }); !errors.Is(err, ErrMigratorNotInitialized) {
+		t.Fatalf("WithMigrationLock() without migrator = %v, want ErrMigratorNotInitialized", err)
+	}
+
+	db, err := sql.Open(BackendSQLite.DriverName(), "file:"+filepath.Join(t.TempDir(), "core.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := InitMigrator(db, BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	ran := false
+	err = WithMigrationLock(func() error {
+		if _, err := RunMigrate("up", 0); !errors.Is(err, ErrMigrationLocked) {
+			t.Errorf("RunMigrate() while locked = %v, want ErrMigrationLocked", err)
+		}
+		return WithMigrationLock(func() error {
+			ran = true
+			return nil
+		})
+	})
+	if !errors.Is(err, ErrMigrationLocked) || ran {
+		t.Fatalf("nested WithMigrationLock() = %v, ran = %v, want ErrMigrationLocked without running", err, ran)
+	}
+
+	if _, err := RunMigrate("up", 1); err != nil {
+		t.Fatalf("RunMigrate() once the lock is released = %v", err)
+	}
+	status, err := GetMigrationStatus()
+	if err != nil || status.Version == nil || *status.Version != 1 {
+		t.Errorf("GetMigrationStatus() = %+v, %v, want version 1", status, err)
+	}
+}
+
+// migrationVersions lists the versions of the embedded migrations of b, failing when one lacks its up or down file
+func migrationVersions(t *testing.T, b Backend) []uint {
+	t.Helper()
+	src, err := migrationsSource(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	var versions []uint
+	v, err := src.First()
+	for err == nil {
+		for direction, read := range map[string]func(uint) (io.ReadCloser, string, error){"up": src.ReadUp, "down": src.ReadDown} {
+			r, _, readErr := read(v)
+			if readErr != nil {
+				t.Errorf("%s migration %d has no %s file: %v", b, v, direction, readErr)
+				continue
+			}
+			r.Close()
+		}
+		versions = append(versions, v)
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return versions
+}
+
+func TestMigrationSets(t *testing.T) {
+	want := migrationVersions(t, BackendSQLite)
+	for _, b := range []Backend{BackendMySQL, BackendPostgres} {
+		if got := migrationVersions(t, b); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s migration versions = %v, want those of sqlite %v", b, got, want)
+		}
+	}
+}
+
+// TestMigrateUpAndDown applies every migration then reverts them one step at a time, on SQLite and on the
+// TEST_DB_BACKEND/TEST_DB_DSN database when set
+func TestMigrateUpAndDown(t *testing.T) {
+	databases := map[Backend]string{BackendSQLite: "file:" + filepath.Join(t.TempDir(), "core.db") + "?_pragma=foreign_keys(1)"}
+	if name := os.Getenv("TEST_DB_BACKEND"); name != "" {
+		b, err := ParseBackend(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		databases[b] = os.Getenv("TEST_DB_DSN")
+	}
+
+	for b, dsn := range databases {
+		t.Run(string(b), func(t *testing.T) {
+			db, err := sql.Open(b.DriverName(), dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if err := InitMigrator(db, b); err != nil {
+				t.Fatal(err)
+			}
+			versions := migrationVersions(t, b)
+
+			if _, err := RunMigrate("up", 0); err != nil {
+				t.Fatal(err)
+			}
+			for i := len(versions) - 1; i >= 0; i-- {
+				status, err := GetMigrationStatus()
+				if err != nil || status.Version == nil || *status.Version != versions[i] || status.Dirty {
+					t.Fatalf("GetMigrationStatus() = %+v, %v, want version %d", status, err, versions[i])
+				}
+				if _, err := RunMigrate("down", -1); err != nil {
+					t.Fatalf("reverting migration %d: %v", versions[i], err)
+				}
+			}
+			status, err := GetMigrationStatus()
+			if err != nil || status.Version != nil || len(status.Pending) != len(versions) {
+				t.Fatalf("GetMigrationStatus() after reverting everything = %+v, %v", status, err)
+			}
+
+			if _, err := RunMigrate("up", 0); err != nil {
+				t.Errorf("migrating again after reverting everything: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package database_actions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// PoolConfig sizes the connection pool shared by the stores and the migrator, zero values keep the database/sql defaults
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// RetryConfig is the exponential backoff of Connect while the database cannot be reached
+//
+// The delay doubles after every failed attempt, up to MaxDelay, and a random part of it is dropped so that
+// instances started together do not retry in lockstep
+type RetryConfig struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Connect creates the database when the backend needs it, opens the pool and checks it answers
+//
+// Each step is retried according to retry, onRetry is called before waiting for the next attempt
+func Connect(ctx context.Context, b Backend, dsn string, pool PoolConfig, retry RetryConfig, onRetry func(attempt int, delay time.Duration, err error)) (*sql.DB, error) {
+	db, err := b.Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the connection pool: %w", err)
+	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	err = withRetry(ctx, retry, onRetry, func() error {
+		if err := b.EnsureDatabase(dsn); err != nil {
+			return err
+		}
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// withRetry runs fn until it succeeds, retry.Attempts are exhausted or ctx is done
+func withRetry(ctx context.Context, retry RetryConfig, onRetry func(attempt int, delay time.Duration, err error), fn func() error) error {
+	delay := retry.InitialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retry.Attempts {
+			return err
+		}
+
+		wait := backoff(delay)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w, giving up: %w", err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+			delay = retry.MaxDelay
+		}
+	}
+}
+
+// backoff waits between half and all of delay
+func backoff(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
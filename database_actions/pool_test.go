@@ -0,0 +1,72 @@
+package database_actions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	retry := RetryConfig{Attempts: 4, InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	unreachable := errors.New("connection refused")
+
+	calls := 0
+	var delays []time.Duration
+	err := withRetry(context.Background(), retry, func(_ int, delay time.Duration, _ error) { delays = append(delays, delay) }, func() error {
+		calls++
+		if calls < 3 {
+			return unreachable
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("withRetry() = %v after %d calls, want success after 3", err, calls)
+	}
+	if len(delays) != 2 || delays[0] > time.Millisecond || delays[1] < time.Millisecond || delays[1] > 2*time.Millisecond {
+		t.Errorf("delays = %v, want within [0.5ms, 1ms] then [1ms, 2ms]", delays)
+	}
+
+	calls = 0
+	err = withRetry(context.Background(), retry, nil, func() error {
+		calls++
+		return unreachable
+	})
+	if !errors.Is(err, unreachable) || calls != retry.Attempts {
+		t.Errorf("withRetry() = %v after %d calls, want the last error after %d", err, calls, retry.Attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = withRetry(ctx, RetryConfig{Attempts: 10, InitialDelay: time.Hour}, nil, func() error { return unreachable })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("withRetry() = %v, want context.Canceled", err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "core.db")
+	db, err := Connect(context.Background(), BackendSQLite, dsn, PoolConfig{MaxOpenConns: 4, MaxIdleConns: 2}, RetryConfig{Attempts: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := db.Stats().MaxOpenConnections; got != 4 {
+		t.Errorf("MaxOpenConnections = %d, want 4", got)
+	}
+
+	if err := InitMigrator(db, BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunMigrate("up", 0); err != nil {
+		t.Fatal(err)
+	}
+	status, err := GetMigrationStatus()
+	if err != nil || status.Version == nil || len(status.Pending) != 0 {
+		t.Fatalf("GetMigrationStatus() = %+v, %v", status, err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("the shared pool was closed by the migrator: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package database_actions
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Asto-42/TechTestJaphy/database_actions")
+
+// Open opens a pool on dsn whose queries are traced as children of the span in their context
+//
+// Queries run outside of a span, such as the startup ping, are not traced
+func (b Backend) Open(dsn string) (*sql.DB, error) {
+	return otelsql.Open(b.DriverName(), dsn,
+		otelsql.WithAttributes(b.dbSystem()),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []sqldriver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
+}
+
+func (b Backend) dbSystem() attribute.KeyValue {
+	switch b {
+	case BackendSQLite:
+		return semconv.DBSystemSqlite
+	case BackendPostgres:
+		return semconv.DBSystemPostgreSQL
+	}
+
+	return semconv.DBSystemMySQL
+}
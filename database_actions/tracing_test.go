@@ -0,0 +1,58 @@
+package database_actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestImportBreedsTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "breeds.csv")
+	os.WriteFile(csvPath, []byte("id,species,pet_size,name,male,female\n1,dog,small,affenpinscher,6000,5000\n2,cat,medium,abyssin,4500,3500\n"), 0o600)
+
+	db, err := BackendSQLite.Open("file:" + filepath.Join(dir, "core.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE breeds (id INTEGER PRIMARY KEY, species TEXT, pet_size TEXT, name TEXT, weight_min REAL, weight_max REAL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Fatalf("queries outside of a span should not be traced, got %d spans", len(recorder.Ended()))
+	}
+
+	stats, err := ImportBreeds(context.Background(), db, BackendSQLite, csvPath, func(string, float64) (string, bool) { return "", false })
+	if err != nil || stats.Imported != 2 {
+		t.Fatalf("ImportBreeds() = %+v, %v", stats, err)
+	}
+
+	var root sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "ImportBreeds" {
+			root = span
+		}
+	}
+	if root == nil {
+		t.Fatal("no ImportBreeds span")
+	}
+	inserts := 0
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == root.SpanContext().SpanID() && span.Name() == "sql.conn.exec" {
+			inserts++
+		}
+	}
+	if inserts != 2 {
+		t.Errorf("got %d insert spans under ImportBreeds, want 2", inserts)
+	}
+}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	"github.com/gorilla/mux"
+)
+
+type migrationRequest struct {
+	Direction string `json:"direction"`
+	Steps     int    `json:"steps"`
+}
+
+type apiKeyRequest struct {
+	Name string `json:"name"`
+	// Role is assigned to the key when set
+	Role Role `json:"role"`
+}
+
+type roleRequest struct {
+	Role Role `json:"role"`
+}
+
+// createdAPIKey is the only response holding the key secret
+type createdAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type migrationResponse struct {
+	Message string                            `json:"message"`
+	Status  *database_actions.MigrationStatus `json:"status"`
+}
+
+// RegisterAdminRoutes registers the back-office routes, restricted to the admin token and to admin callers
+func (a *App) RegisterAdminRoutes(r *mux.Router) {
+	r.Use(a.requireAdmin)
+	r.HandleFunc("/migrations", a.GetMigrationStatus).Methods("GET")
+	r.HandleFunc("/migrations", a.RunMigrations).Methods("POST")
+	r.HandleFunc("/api-keys", a.ListAPIKeys).Methods("GET")
+	r.HandleFunc("/api-keys", a.CreateAPIKey).Methods("POST")
+	r.HandleFunc("/api-keys/{id:[0-9]+}", a.RevokeAPIKey).Methods("DELETE")
+	r.HandleFunc("/roles", a.ListRoleAssignments).Methods("GET")
+	r.HandleFunc("/roles/{subject}", a.AssignRole).Methods("PUT")
+	r.HandleFunc("/roles/{subject}", a.UnassignRole).Methods("DELETE")
+}
+
+// requireAdmin accepts `Authorization: Bearer <AdminToken>`, used to bootstrap the first admin, or callers with the admin role
+func (a *App) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if found && a.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.AdminToken)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.Auth == nil {
+			a.log(r).Warn("Rejected admin request")
+			writeProblem(w, http.StatusUnauthorized, "The admin token is required")
+			return
+		}
+		a.authenticate(a.require(PermissionAdmin, next.ServeHTTP)).ServeHTTP(w, r)
+	})
+}
+
+func (a *App) GetMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := database_actions.GetMigrationStatus()
+	if errors.Is(err, database_actions.ErrMigratorNotInitialized) {
+		writeProblem(w, http.StatusNotImplemented, "Migrations are not available with this store")
+		return
+	}
+	if err != nil {
+		a.log(r).Error("Failed to read migration status", "err", err)
+		writeProblem(w, http.StatusInternalServerError, "Failed to read migration status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// RunMigrations applies migrations, body is `{"direction": "up"|"down", "steps": n}`
+//
+// 'steps' as 0 runs all migrations in the given direction
+func (a *App) RunMigrations(w http.ResponseWriter, r *http.Request) {
+	var req migrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Steps < 0 {
+		writeProblem(w, http.StatusBadRequest, "steps must be positive")
+		return
+	}
+
+	steps := req.Steps
+	switch req.Direction {
+	case "up":
+	case "down":
+		steps = -steps
+	default:
+		writeProblem(w, http.StatusBadRequest, "direction must be 'up' or 'down'")
+		return
+	}
+
+	msg, err := database_actions.RunMigrate(req.Direction, steps)
+	if errors.Is(err, database_actions.ErrMigratorNotInitialized) {
+		writeProblem(w, http.StatusNotImplemented, "Migrations are not available with this store")
+		return
+	}
+	if errors.Is(err, database_actions.ErrMigrationLocked) {
+		a.log(r).Warn("Migrations are already running", "err", err)
+		writeProblem(w, http.StatusConflict, "Migrations are already running")
+		return
+	}
+	if err != nil {
+		a.log(r).Error("Failed to run migrations", "err", err)
+		writeProblem(w, http.StatusInternalServerError, "Failed to run migrations")
+		return
+	}
+	a.log(r).Info(msg, "direction", req.Direction, "steps", req.Steps)
+
+	status, err := database_actions.GetMigrationStatus()
+	if err != nil {
+		a.log(r).Error("Failed to read migration status", "err", err)
+		writeProblem(w, http.StatusInternalServerError, "Failed to read migration status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(migrationResponse{Message: msg, Status: status})
+}
+
+func (a *App) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "api_keys.list")
+	defer cancel()
+	keys, err := a.APIKeys.List(ctx)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to list API keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey generates a key, its secret is only ever returned by this call
+func (a *App) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		writeProblem(w, http.StatusBadRequest, "Invalid request body, a name is required")
+		return
+	}
+	if req.Role != "" && !req.Role.Valid() {
+		writeProblem(w, http.StatusBadRequest, "Unknown role")
+		return
+	}
+
+	secret, prefix, err := newAPIKeySecret()
+	if err != nil {
+		a.log(r).Error("Failed to generate API key", "err", err)
+		writeProblem(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	ctx, cancel := a.queryContext(r, "api_keys.create")
+	defer cancel()
+	key, err := a.APIKeys.Create(ctx, APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    prefix,
+		Hash:      hashAPIKey(secret),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to create API key")
+		return
+	}
+	a.log(r).Info("API key created", "api_key_id", key.ID, "name", key.Name)
+	if req.Role != "" {
+		err = a.Roles.Assign(ctx, RoleAssignment{Subject: apiKeySubject(key.ID), Role: req.Role, AssignedAt: key.CreatedAt})
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to assign role to API key", "api_key_id", key.ID)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAPIKey{APIKey: key, Key: secret})
+}
+
+func (a *App) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "api_keys.revoke")
+	defer cancel()
+	err := a.APIKeys.Revoke(ctx, id, time.Now().UTC().Truncate(time.Second))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		writeProblem(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to revoke API key")
+		return
+	}
+	a.log(r).Info("API key revoked", "api_key_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) ListRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "roles.list")
+	defer cancel()
+	assignments, err := a.Roles.List(ctx)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to list role assignments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// AssignRole sets the role of a subject, `api-key:<id>` for API keys or the sub claim of JWTs
+func (a *App) AssignRole(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Role.Valid() {
+		writeProblem(w, http.StatusBadRequest, "Invalid request body, role must be viewer, editor or admin")
+		return
+	}
+
+	assignment := RoleAssignment{
+		Subject:    mux.Vars(r)["subject"],
+		Role:       req.Role,
+		AssignedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	ctx, cancel := a.queryContext(r, "roles.assign")
+	defer cancel()
+	if err := a.Roles.Assign(ctx, assignment); err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to assign role")
+		return
+	}
+	a.log(r).Info("Role assigned", "subject", assignment.Subject, "role", assignment.Role)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func (a *App) UnassignRole(w http.ResponseWriter, r *http.Request) {
+	subject := mux.Vars(r)["subject"]
+
+	ctx, cancel := a.queryContext(r, "roles.unassign")
+	defer cancel()
+	err := a.Roles.Unassign(ctx, subject)
+	if errors.Is(err, ErrRoleNotAssigned) {
+		writeProblem(w, http.StatusNotFound, "No role assigned")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to unassign role")
+		return
+	}
+	a.log(r).Info("Role unassigned", "subject", subject)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+)
+
+// initTestMigrator points the migrator at a fresh SQLite file, no migration applied
+func initTestMigrator(t *testing.T) {
+	t.Helper()
+	db, err := sql.Open(database_actions.BackendSQLite.DriverName(), "file:"+filepath.Join(t.TempDir(), "core.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database_actions.InitMigrator(db, database_actions.BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkProblem fails t unless rec answered a problem details error with status
+func checkProblem(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Errorf("status = %d, want %d (%s)", rec.Code, status, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", ct)
+	}
+}
+
+func TestMigrationEndpoints(t *testing.T) {
+	do := newAuthTestRouter(t)
+	admin := http.Header{"Authorization": {"Bearer admin-secret"}}
+	viewer := http.Header{"X-Api-Key": {createTestAPIKey(t, do, "")}}
+	adminKey := http.Header{"X-Api-Key": {createTestAPIKey(t, do, RoleAdmin)}}
+	initTestMigrator(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		body       string
+		wantStatus int
+	}{
+		{name: "anonymous status", method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "anonymous run", method: http.MethodPost, body: `{"direction":"up"}`, wantStatus: http.StatusUnauthorized},
+		{name: "wrong admin token", method: http.MethodPost, header: http.Header{"Authorization": {"Bearer guess"}}, body: `{"direction":"up"}`, wantStatus: http.StatusUnauthorized},
+		{name: "viewer status", method: http.MethodGet, header: viewer, wantStatus: http.StatusForbidden},
+		{name: "viewer run", method: http.MethodPost, header: viewer, body: `{"direction":"up"}`, wantStatus: http.StatusForbidden},
+		{name: "admin role status", method: http.MethodGet, header: adminKey, wantStatus: http.StatusOK},
+		{name: "invalid body", method: http.MethodPost, header: admin, body: `{"direction":`, wantStatus: http.StatusBadRequest},
+		{name: "invalid direction", method: http.MethodPost, header: admin, body: `{"direction":"sideways"}`, wantStatus: http.StatusBadRequest},
+		{name: "negative steps", method: http.MethodPost, header: admin, body: `{"direction":"up","steps":-1}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, "/admin/migrations", tt.header, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code >= http.StatusBadRequest {
+				checkProblem(t, rec, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMigrationStatusAndRun(t *testing.T) {
+	do := newAuthTestRouter(t)
+	admin := http.Header{"Authorization": {"Bearer admin-secret"}}
+	initTestMigrator(t)
+
+	rec := do(http.MethodGet, "/admin/migrations", admin, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	var status database_actions.MigrationStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != nil || len(status.Pending) == 0 {
+		t.Fatalf("status = %+v, want every migration pending", status)
+	}
+	first := status.Pending[0]
+	if first.Version != 1 || first.Identifier != "breeds" || !strings.Contains(first.SQL, "CREATE TABLE IF NOT EXISTS breeds") {
+		t.Errorf("first pending migration = %+v, want 1_breeds with its SQL", first)
+	}
+
+	rec = do(http.MethodPost, "/admin/migrations", admin, `{"direction":"up","steps":1}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("run: %d (%s)", rec.Code, rec.Body.String())
+	}
+	var run migrationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Message != "Successfully ran 1 up migration" || run.Status == nil || run.Status.Version == nil || *run.Status.Version != 1 {
+		t.Errorf("run = %+v, want version 1", run)
+	}
+	if len(run.Status.Pending) != len(status.Pending)-1 {
+		t.Errorf("%d migrations pending after one step, want %d", len(run.Status.Pending), len(status.Pending)-1)
+	}
+
+	err := database_actions.WithMigrationLock(func() error {
+		rec = do(http.MethodPost, "/admin/migrations", admin, `{"direction":"up"}`)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProblem(t, rec, http.StatusConflict)
+}
+
+func TestMigrationsWithMemoryStore(t *testing.T) {
+	do := newAuthTestRouter(t)
+	admin := http.Header{"Authorization": {"Bearer admin-secret"}}
+	if err := database_actions.InitMigrator(nil, database_actions.BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	checkProblem(t, do(http.MethodGet, "/admin/migrations", admin, ""), http.StatusNotImplemented)
+	checkProblem(t, do(http.MethodPost, "/admin/migrations", admin, `{"direction":"up"}`), http.StatusNotImplemented)
+}
@@ -1,42 +1,87 @@
 package internal
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
 	charmLog "github.com/charmbracelet/log"
 	"github.com/gorilla/mux"
-	"fmt"
-	"io"
-	"bytes"
 )
 
 type App struct {
-	logger *charmLog.Logger
-	DB     *sql.DB
+	logger   *charmLog.Logger
+	Store    BreedStore
+	APIKeys  APIKeyStore
+	Roles    RoleStore
+	Pets     PetStore
+	Products ProductStore
+	Growth   GrowthCurveStore
+	// Auth authenticates the callers of the /v1 routes, which are anonymous when nil
+	Auth *Authenticator
+	// RateLimiter limits the callers of the /v1 routes, which are unlimited when nil
+	RateLimiter *RateLimiter
+	// Metrics records the requests served by LogRequests, when set
+	Metrics *Metrics
+	// Validator checks the requests of the /v1 routes against the OpenAPI spec, when set
+	Validator *SpecValidator
+	// QueryTimeouts bounds the database calls of the handlers
+	QueryTimeouts QueryTimeouts
+	// SizeClasses derive the size of the breeds created, updated or imported through the API
+	SizeClasses SizeClasses
+	AdminToken  string
 }
 
 func NewApp(logger *charmLog.Logger) *App {
 	return &App{
-		logger: logger,
+		logger:      logger,
+		SizeClasses: DefaultSizeClasses,
 	}
 }
 
 func (a *App) RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/breeds/{id}", a.GetBreedByID).Methods("GET")
-	r.HandleFunc("/breeds/search", a.SearchBreeds).Methods("GET")
-	r.HandleFunc("/breeds/{id:[0-9]+}", a.UpdateBreed).Methods("PUT")
-	r.HandleFunc("/breeds/{id:[0-9]+}", a.DeleteBreed).Methods("DELETE")
-	r.HandleFunc("/breeds", a.GetBreeds).Methods("GET")
-	r.HandleFunc("/breeds", a.CreateBreed).Methods("POST")
-
-	// fmt.Println("🔍 Routes enregistrées :")
-    // r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
-    //     t, _ := route.GetPathTemplate()
-    //     fmt.Printf("📄 Route enregistrée : %s\n", t)
-    //     return nil
-    // })
+	if a.Auth != nil {
+		r.Use(a.authenticate)
+	}
+	if a.RateLimiter != nil {
+		r.Use(a.rateLimit)
+	}
+	if a.Validator != nil {
+		r.Use(a.validate)
+	}
+	r.HandleFunc("/breeds/search", a.require(PermissionBreedsRead, a.SearchBreeds)).Methods("GET")
+	r.HandleFunc("/breeds/mix", a.require(PermissionBreedsRead, a.EstimateMix)).Methods("GET")
+	r.HandleFunc("/breeds/stats", a.require(PermissionBreedsRead, a.GetBreedStats)).Methods("GET")
+	r.HandleFunc("/breeds/size-mismatches", a.require(PermissionBreedsRead, a.ListSizeMismatches)).Methods("GET")
+	r.HandleFunc("/breeds/{id:[0-9]+}/similar", a.require(PermissionBreedsRead, a.GetSimilarBreeds)).Methods("GET")
+	r.HandleFunc("/breeds/{id:[0-9]+}/growth", a.require(PermissionBreedsRead, a.GetBreedGrowth)).Methods("GET")
+	r.HandleFunc("/breeds/{id:[0-9]+}/growth-curve", a.require(PermissionBreedsRead, a.GetBreedGrowthCurve)).Methods("GET")
+	r.HandleFunc("/breeds/{id:[0-9]+}/growth-curve", a.require(PermissionBreedsWrite, a.SetBreedGrowthCurve)).Methods("PUT")
+	r.HandleFunc("/breeds/{id:[0-9]+}/growth-curve", a.require(PermissionBreedsWrite, a.DeleteBreedGrowthCurve)).Methods("DELETE")
+	r.HandleFunc("/breeds/{id:[0-9]+}", a.require(PermissionBreedsRead, a.GetBreedByID)).Methods("GET")
+	r.HandleFunc("/breeds/{id:[0-9]+}", a.require(PermissionBreedsWrite, a.UpdateBreed)).Methods("PUT")
+	r.HandleFunc("/breeds/{id:[0-9]+}", a.require(PermissionBreedsWrite, a.DeleteBreed)).Methods("DELETE")
+	r.HandleFunc("/breeds/{id:[0-9]+}", a.require(PermissionBreedsWrite, a.PatchBreed)).Methods("PATCH")
+	r.HandleFunc("/breeds/import", a.require(PermissionBreedsWrite, a.ImportBreeds)).Methods("POST")
+	r.HandleFunc("/breeds", a.require(PermissionBreedsRead, a.GetBreeds)).Methods("GET")
+	r.HandleFunc("/breeds", a.require(PermissionBreedsWrite, a.CreateBreed)).Methods("POST")
+	r.HandleFunc("/growth-curves/{species}/{pet_size}", a.require(PermissionBreedsWrite, a.SetGrowthCurve)).Methods("PUT")
+	r.HandleFunc("/growth-curves", a.require(PermissionBreedsRead, a.ListGrowthCurves)).Methods("GET")
+	r.HandleFunc("/nutrition/requirements", a.require(PermissionBreedsRead, a.GetNutritionRequirements)).Methods("GET")
+	r.HandleFunc("/products/recommendations", a.require(PermissionProductsRead, a.RecommendProducts)).Methods("GET")
+	r.HandleFunc("/products/{id:[0-9]+}", a.require(PermissionProductsRead, a.GetProduct)).Methods("GET")
+	r.HandleFunc("/products/{id:[0-9]+}", a.require(PermissionProductsWrite, a.UpdateProduct)).Methods("PUT")
+	r.HandleFunc("/products/{id:[0-9]+}", a.require(PermissionProductsWrite, a.DeleteProduct)).Methods("DELETE")
+	r.HandleFunc("/products", a.require(PermissionProductsRead, a.ListProducts)).Methods("GET")
+	r.HandleFunc("/products", a.require(PermissionProductsWrite, a.CreateProduct)).Methods("POST")
+	r.HandleFunc("/pets/{id:[0-9]+}", a.require(PermissionPetsRead, a.GetPet)).Methods("GET")
+	r.HandleFunc("/pets/{id:[0-9]+}", a.require(PermissionPetsWrite, a.UpdatePet)).Methods("PUT")
+	r.HandleFunc("/pets/{id:[0-9]+}", a.require(PermissionPetsWrite, a.DeletePet)).Methods("DELETE")
+	r.HandleFunc("/pets", a.require(PermissionPetsRead, a.ListPets)).Methods("GET")
+	r.HandleFunc("/pets", a.require(PermissionPetsWrite, a.CreatePet)).Methods("POST")
 }
 
 type Breed struct {
@@ -44,64 +89,81 @@ type Breed struct {
 	Name          string  `json:"name"`
 	Species       string  `json:"species"`
 	AverageWeight float64 `json:"average_weight"`
+	PetSize       string  `json:"-"`
+	WeightMin     float64 `json:"-"`
+	WeightMax     float64 `json:"-"`
 }
 
-func (a *App) GetBreedByID(w http.ResponseWriter, r *http.Request) {
-    vars := mux.Vars(r)
-    idStr := vars["id"]
-    id, err := strconv.Atoi(idStr)
-    if err != nil {
-        a.logger.Error(fmt.Sprintf("ID invalide : %s", err.Error()))
-        http.Error(w, "Invalid ID format", http.StatusBadRequest)
-        return
-    }
-    var breed Breed
-    err = a.DB.QueryRow(`
-    SELECT 
-        id, 
-        name, 
-        species, 
-        (weight_min + weight_max) / 2 AS average_weight 
-    FROM breeds
-    WHERE id = ?
-	`, id).Scan(&breed.ID, &breed.Name, &breed.Species, &breed.AverageWeight)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            a.logger.Warn(fmt.Sprintf("Aucun breed trouvé avec ID : %d", id))
-            http.Error(w, "Breed not found", http.StatusNotFound)
-        } else {
-            a.logger.Error(fmt.Sprintf("Erreur de base de données : %s", err.Error()))
-            http.Error(w, "Failed to fetch breed", http.StatusInternalServerError)
-        }
-        return
-    }
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(breed)
-}
+// unknownPetSize is the size of the breeds created through the API whose species has no size classes
+const unknownPetSize = "Unknown"
 
+// withWeights sets the stored weight range around AverageWeight, the only weight exposed by the API
+func (b Breed) withWeights() Breed {
+	b.WeightMin = b.AverageWeight - 1
+	b.WeightMax = b.AverageWeight + 1
+	return b
+}
 
+// AdultWeight returns the average adult weight of sex, both sexes averaged when sex is empty
+//
+// WeightMin and WeightMax hold the male and female averages of breeds.csv
+func (b Breed) AdultWeight(sex Sex) float64 {
+	switch sex {
+	case SexMale:
+		return b.WeightMin
+	case SexFemale:
+		return b.WeightMax
+	}
+	return (b.WeightMin + b.WeightMax) / 2
+}
 
-func (a *App) GetBreeds(w http.ResponseWriter, r *http.Request) {
-	rows, err := a.DB.Query(`
-    SELECT 
-        id, 
-        name, 
-        species, 
-        (weight_min + weight_max) / 2 AS average_weight 
-    FROM breeds
-`)
+func (a *App) GetBreedByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		a.log(r).Warn("Invalid breed ID", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+	ctx, cancel := a.queryContext(r, "breeds.get")
+	defer cancel()
+	breed, err := a.Store.Get(ctx, id)
 	if err != nil {
-		a.logger.Error(err.Error())
-		http.Error(w, "Failed to fetch breeds", http.StatusInternalServerError)
+		if errors.Is(err, ErrBreedNotFound) {
+			a.log(r).Warn("Breed not found", "breed_id", id)
+			writeProblem(w, http.StatusNotFound, "Breed not found")
+		} else {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch breed", "breed_id", id)
+		}
 		return
 	}
-	defer rows.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breed)
+}
 
-	var breeds []Breed
-	for rows.Next() {
-		var breed Breed
-		rows.Scan(&breed.ID, &breed.Name, &breed.Species, &breed.AverageWeight)
-		breeds = append(breeds, breed)
+// GetBreeds lists the breeds by id, a page of `limit` breeds after the id `after_id` when given
+func (a *App) GetBreeds(w http.ResponseWriter, r *http.Request) {
+	var filter BreedFilter
+	for param, dest := range map[string]*int{"limit": &filter.Limit, "after_id": &filter.AfterID} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeProblem(w, http.StatusBadRequest, param+" must be a positive integer")
+			return
+		}
+		*dest = n
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.list")
+	defer cancel()
+	breeds, err := a.Store.Search(ctx, filter)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch breeds")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -109,140 +171,198 @@ func (a *App) GetBreeds(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *App) CreateBreed(w http.ResponseWriter, r *http.Request) {
-    body, err := io.ReadAll(r.Body)
-    if err != nil {
-        a.logger.Error(fmt.Sprintf("Failed to read request body: %s", err.Error()))
-        http.Error(w, "Failed to read request body", http.StatusBadRequest)
-        return
-    }
-    r.Body = io.NopCloser(bytes.NewBuffer(body))
-    var breed Breed
-    if err := json.NewDecoder(r.Body).Decode(&breed); err != nil {
-        a.logger.Error(fmt.Sprintf("Invalid request body: %s", err.Error()))
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        return
-    }
-    result, err := a.DB.Exec(`
-        INSERT INTO breeds (name, species, pet_size, weight_min, weight_max)
-        VALUES (?, ?, ?, ?, ?)
-    `, breed.Name, breed.Species, "Unknown", breed.AverageWeight-1, breed.AverageWeight+1)
-    if err != nil {
-        a.logger.Error(fmt.Sprintf("Failed to create breed: %s", err.Error()))
-        http.Error(w, "Failed to create breed", http.StatusInternalServerError)
-        return
-    }
-    lastInsertID, err := result.LastInsertId()
-    if err != nil {
-        a.logger.Error(fmt.Sprintf("Failed to retrieve last insert ID: %s", err.Error()))
-        http.Error(w, "Failed to retrieve last insert ID", http.StatusInternalServerError)
-        return
-    }
-    breed.ID = int(lastInsertID)
-    rows, err := a.DB.Query("SELECT id, name, species FROM breeds WHERE id = ?", breed.ID)
-    if err != nil {
-        a.logger.Error(fmt.Sprintf("Failed to query breed after insertion: %s", err.Error()))
-        http.Error(w, "Failed to query breed after insertion", http.StatusInternalServerError)
-        return
-    }
-    defer rows.Close()
-    for rows.Next() {
-        var id int
-        var name, species string
-        rows.Scan(&id, &name, &species)
-    }
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusCreated)
-    if err := json.NewEncoder(w).Encode(breed); err != nil {
-        a.logger.Error(fmt.Sprintf("Failed to encode response: %s", err.Error()))
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-    }
+	var breed Breed
+	if err := json.NewDecoder(r.Body).Decode(&breed); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	breed = a.withPetSize(breed.withWeights(), unknownPetSize)
+	ctx, cancel := a.queryContext(r, "breeds.create")
+	defer cancel()
+	created, err := a.Store.Create(ctx, breed)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to create breed")
+		return
+	}
+	breed.ID = created.ID
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(breed); err != nil {
+		a.log(r).Error("Failed to encode response", "err", err)
+		writeProblem(w, http.StatusInternalServerError, "Failed to encode response")
+	}
 }
 
-
-
 func (a *App) UpdateBreed(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
+	id, _ := strconv.Atoi(vars["id"])
 
 	var breed Breed
 	if err := json.NewDecoder(r.Body).Decode(&breed); err != nil {
-		a.logger.Error(err.Error())
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	breed = breed.withWeights()
+	breed.ID = id
+
+	ctx, cancel := a.queryContext(r, "breeds.update")
+	defer cancel()
+	existing, err := a.Store.Get(ctx, id)
+	if err == nil {
+		breed = a.withPetSize(breed, existing.PetSize)
+	} else if !errors.Is(err, ErrBreedNotFound) {
+		a.storeFailed(w, r, ctx, err, "Failed to update breed")
 		return
 	}
 
-	_, err := a.DB.Exec(`
-    UPDATE breeds 
-    SET name = ?, species = ?, weight_min = ?, weight_max = ? 
-    WHERE id = ?`,
-    breed.Name, breed.Species, breed.AverageWeight-1, breed.AverageWeight+1, id)
+	err = a.Store.Update(ctx, breed)
 	if err != nil {
-		a.logger.Error(err.Error())
-		http.Error(w, "Failed to update breed", http.StatusInternalServerError)
+		a.storeFailed(w, r, ctx, err, "Failed to update breed")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (a *App) DeleteBreed(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+// breedPatch is a JSON merge patch of a breed, absent fields are kept
+type breedPatch struct {
+	Name          *string  `json:"name"`
+	Species       *string  `json:"species"`
+	AverageWeight *float64 `json:"average_weight"`
+}
 
-	_, err := a.DB.Exec("DELETE FROM breeds WHERE id = ?", id)
+// PatchBreed updates the fields of the breed present in the body and returns the breed
+func (a *App) PatchBreed(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var patch breedPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.update")
+	defer cancel()
+	breed, err := a.Store.Get(ctx, id)
+	if errors.Is(err, ErrBreedNotFound) {
+		writeProblem(w, http.StatusNotFound, "Breed not found")
+		return
+	}
 	if err != nil {
-		a.logger.Error(err.Error())
-		http.Error(w, "Failed to delete breed", http.StatusInternalServerError)
+		a.storeFailed(w, r, ctx, err, "Failed to update breed", "breed_id", id)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if patch.Name != nil {
+		breed.Name = *patch.Name
+	}
+	if patch.Species != nil {
+		breed.Species = *patch.Species
+	}
+	if patch.AverageWeight != nil {
+		breed.AverageWeight = *patch.AverageWeight
+		breed = breed.withWeights()
+	}
+	breed = a.withPetSize(breed, breed.PetSize)
+	if err := a.Store.Update(ctx, breed); err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to update breed", "breed_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breed)
 }
 
-func (a *App) SearchBreeds(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	species := queryParams.Get("species")
-	weight := queryParams.Get("weight")
-
-	query := `
-    SELECT 
-        id, 
-        name, 
-        species, 
-        (weight_min + weight_max) / 2 AS average_weight 
-    FROM breeds 
-    WHERE 1=1
-`
-	args := []interface{}{}
-
-	if species != "" {
-		query += " AND species = ?"
-		args = append(args, species)
+// importResult is the answer of ImportBreeds
+type importResult struct {
+	Imported int `json:"imported"`
+}
+
+// ImportBreeds creates the breeds of a CSV body in the breeds.csv format, the pet_size column being only kept for
+// the species without size classes
+//
+// Breeds are created one by one: when one fails, those before it stay imported
+func (a *App) ImportBreeds(w http.ResponseWriter, r *http.Request) {
+	records, err := database_actions.ParseBreeds(r.Body)
+	if err != nil {
+		a.log(r).Warn("Invalid CSV body", "err", err)
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if weight != "" {
-		weightVal, err := strconv.ParseFloat(weight, 64)
-		if err == nil {
-			query += " AND average_weight <= ?"
-			args = append(args, weightVal)
+	ctx, cancel := a.queryContext(r, "breeds.import")
+	defer cancel()
+	var stats database_actions.ImportStats
+	defer func() {
+		if a.Metrics != nil {
+			a.Metrics.ObserveImport(stats)
+		}
+	}()
+	for _, record := range records {
+		breed := Breed{
+			Name:      record.Name,
+			Species:   record.Species,
+			WeightMin: record.WeightMin,
+			WeightMax: record.WeightMax,
+		}
+		_, err := a.Store.Create(ctx, a.withPetSize(breed, record.PetSize))
+		if err != nil {
+			stats.Failed++
+			a.storeFailed(w, r, ctx, err, "Failed to import breeds", "imported", stats.Imported)
+			return
 		}
+		stats.Imported++
 	}
+	a.log(r).Info("Breeds imported", "imported", stats.Imported)
 
-	rows, err := a.DB.Query(query, args...)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(importResult{Imported: stats.Imported})
+}
+
+func (a *App) DeleteBreed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+
+	ctx, cancel := a.queryContext(r, "breeds.delete")
+	defer cancel()
+	err := a.Store.Delete(ctx, id)
 	if err != nil {
-		a.logger.Error(err.Error())
-		http.Error(w, "Failed to search breeds", http.StatusInternalServerError)
+		a.storeFailed(w, r, ctx, err, "Failed to delete breed")
 		return
 	}
-	defer rows.Close()
 
-	var breeds []Breed
-	for rows.Next() {
-		var breed Breed
-		rows.Scan(&breed.ID, &breed.Name, &breed.Species, &breed.AverageWeight)
-		breeds = append(breeds, breed)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) SearchBreeds(w http.ResponseWriter, r *http.Request) {
+	filter := searchFilter(r.URL.Query())
+
+	ctx, cancel := a.queryContext(r, "breeds.search")
+	defer cancel()
+	breeds, err := a.Store.Search(ctx, filter)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to search breeds")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(breeds)
-}
\ No newline at end of file
+}
+
+// searchFilter reads the species and weight parameters of SearchBreeds, an invalid weight being ignored
+func searchFilter(query url.Values) BreedFilter {
+	filter := BreedFilter{Species: query.Get("species")}
+
+	weight := query.Get("weight")
+	if weight != "" {
+		weightVal, err := strconv.ParseFloat(weight, 64)
+		if err == nil {
+			filter.MaxWeight = &weightVal
+		}
+	}
+	return filter
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+var fixtureBreeds = []database_actions.BreedRecord{
+	{Species: "dog", PetSize: "small", Name: "affenpinscher", WeightMin: 6000, WeightMax: 5000},
+	{Species: "dog", PetSize: "large", Name: "akita", WeightMin: 45000, WeightMax: 35000},
+	{Species: "cat", PetSize: "medium", Name: "abyssinian", WeightMin: 4000, WeightMax: 3000},
+}
+
+func newTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	return r
+}
+
+func TestBreedHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantNames  []string
+	}{
+		{name: "list", method: http.MethodGet, path: "/v1/breeds", wantStatus: http.StatusOK, wantNames: []string{"affenpinscher", "akita", "abyssinian"}},
+		{name: "get", method: http.MethodGet, path: "/v1/breeds/2", wantStatus: http.StatusOK, wantNames: []string{"akita"}},
+		{name: "get unknown", method: http.MethodGet, path: "/v1/breeds/42", wantStatus: http.StatusNotFound},
+		{name: "get non numeric id", method: http.MethodGet, path: "/v1/breeds/abc", wantStatus: http.StatusNotFound},
+		{name: "search species", method: http.MethodGet, path: "/v1/breeds/search?species=dog", wantStatus: http.StatusOK, wantNames: []string{"affenpinscher", "akita"}},
+		{name: "search weight", method: http.MethodGet, path: "/v1/breeds/search?weight=6000", wantStatus: http.StatusOK, wantNames: []string{"affenpinscher", "abyssinian"}},
+		{name: "search species and weight", method: http.MethodGet, path: "/v1/breeds/search?species=dog&weight=6000", wantStatus: http.StatusOK, wantNames: []string{"affenpinscher"}},
+		{name: "create", method: http.MethodPost, path: "/v1/breeds", body: `{"name":"beagle","species":"dog","average_weight":12000}`, wantStatus: http.StatusCreated, wantNames: []string{"beagle"}},
+		{name: "create invalid body", method: http.MethodPost, path: "/v1/breeds", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "update", method: http.MethodPut, path: "/v1/breeds/1", body: `{"name":"affen","species":"dog","average_weight":5000}`, wantStatus: http.StatusOK},
+		{name: "update invalid body", method: http.MethodPut, path: "/v1/breeds/1", body: `nope`, wantStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/v1/breeds/3", wantStatus: http.StatusNoContent},
+		{name: "list page", method: http.MethodGet, path: "/v1/breeds?limit=1&after_id=1", wantStatus: http.StatusOK, wantNames: []string{"akita"}},
+		{name: "list invalid limit", method: http.MethodGet, path: "/v1/breeds?limit=many", wantStatus: http.StatusBadRequest},
+		{name: "patch", method: http.MethodPatch, path: "/v1/breeds/2", body: `{"name":"akita inu"}`, wantStatus: http.StatusOK, wantNames: []string{"akita inu"}},
+		{name: "patch unknown", method: http.MethodPatch, path: "/v1/breeds/42", body: `{"name":"akita inu"}`, wantStatus: http.StatusNotFound},
+		{name: "import", method: http.MethodPost, path: "/v1/breeds/import", body: "id,species,pet_size,name,male,female\n1,dog,medium,beagle,12000,11000\n", wantStatus: http.StatusCreated},
+		{name: "import invalid CSV", method: http.MethodPost, path: "/v1/breeds/import", body: "id,species\n1,dog\n", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantNames == nil {
+				return
+			}
+			if got := decodeNames(t, rec.Body.Bytes()); strings.Join(got, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("names = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestPatchKeepsOtherFields(t *testing.T) {
+	r := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/v1/breeds/2", strings.NewReader(`{"average_weight":40000}`)))
+	var breed Breed
+	if err := json.Unmarshal(rec.Body.Bytes(), &breed); err != nil {
+		t.Fatal(err)
+	}
+	if breed.Name != "akita" || breed.Species != "dog" || breed.AverageWeight != 40000 {
+		t.Errorf("breed = %+v, want akita weighing 40000", breed)
+	}
+}
+
+func TestUpdateThenGet(t *testing.T) {
+	r := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/breeds/1", strings.NewReader(`{"name":"affen","species":"dog","average_weight":5000}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/1", nil))
+	var breed Breed
+	if err := json.Unmarshal(rec.Body.Bytes(), &breed); err != nil {
+		t.Fatal(err)
+	}
+	if breed.Name != "affen" || breed.AverageWeight != 5000 {
+		t.Errorf("breed = %+v, want the updated breed", breed)
+	}
+}
+
+// decodeNames accepts either a single breed or a list of breeds
+func decodeNames(t *testing.T, body []byte) []string {
+	t.Helper()
+	var breeds []Breed
+	if err := json.Unmarshal(body, &breeds); err != nil {
+		var breed Breed
+		if err := json.Unmarshal(body, &breed); err != nil {
+			t.Fatalf("invalid JSON body %s: %s", body, err)
+		}
+		breeds = []Breed{breed}
+	}
+	names := make([]string, len(breeds))
+	for i, breed := range breeds {
+		names[i] = breed.Name
+	}
+	return names
+}
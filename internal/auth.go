@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiKeyPrefix = "jpk_"
+
+// ErrAuthUnavailable wraps the store failures met while authenticating, which are not the caller's fault
+var ErrAuthUnavailable = errors.New("authentication unavailable")
+
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+	loggerKey
+	requestInfoKey
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	// Subject is "api-key:<id>" for API keys and the sub claim for JWTs
+	Subject string
+	Method  string
+	// Role is the role assigned to Subject, DefaultRole when none is
+	Role Role
+	// Claims holds the JWT claims, nil for API keys
+	Claims JWTClaims
+}
+
+// IdentityFromContext returns the caller attached by the authentication middleware
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(*Identity)
+	return identity, ok
+}
+
+func withIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// Authenticator identifies callers by API key (`X-API-Key` or `Authorization: Bearer`) or JWT bearer token
+type Authenticator struct {
+	Keys  APIKeyStore
+	Roles RoleStore
+	// JWKS verifies bearer tokens, JWTs are rejected when nil
+	JWKS     *JWKS
+	Issuer   string
+	Audience string
+}
+
+// Authenticate returns the identity of the credentials carried by r, with its role
+func (au *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	identity, err := au.identify(r)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.Role = DefaultRole
+	assignment, err := au.Roles.Get(r.Context(), identity.Subject)
+	if err == nil {
+		identity.Role = assignment.Role
+	} else if !errors.Is(err, ErrRoleNotAssigned) {
+		return nil, fmt.Errorf("%w: role lookup failed: %s", ErrAuthUnavailable, err)
+	}
+	return identity, nil
+}
+
+func (au *Authenticator) identify(r *http.Request) (*Identity, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return au.authenticateAPIKey(r.Context(), key)
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return nil, errors.New("missing credentials")
+	}
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return au.authenticateAPIKey(r.Context(), token)
+	}
+	if au.JWKS == nil {
+		return nil, errors.New("bearer tokens are not accepted")
+	}
+	claims, err := au.JWKS.Verify(token, au.Issuer, au.Audience, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Subject: claims["sub"].(string), Method: "jwt", Claims: claims}, nil
+}
+
+func (au *Authenticator) authenticateAPIKey(ctx context.Context, secret string) (*Identity, error) {
+	key, err := au.Keys.FindByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, errors.New("unknown api key")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: api key lookup failed: %s", ErrAuthUnavailable, err)
+	}
+	if key.RevokedAt != nil {
+		return nil, errors.New("revoked api key")
+	}
+	return &Identity{Subject: apiKeySubject(key.ID), Method: "api_key"}, nil
+}
+
+func apiKeySubject(id int) string {
+	return "api-key:" + strconv.Itoa(id)
+}
+
+// authenticate rejects anonymous requests with 401 and attaches the caller identity to the request context
+func (a *App) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.authFailuresExceeded(w, r) {
+			return
+		}
+		authCtx, cancel := a.queryContext(r, "auth")
+		identity, err := a.Auth.Authenticate(r.WithContext(authCtx))
+		cancel()
+		if errors.Is(err, ErrAuthUnavailable) {
+			a.log(r).Error("Authentication unavailable", "err", err)
+			writeProblem(w, http.StatusServiceUnavailable, "Authentication is temporarily unavailable")
+			return
+		}
+		if err != nil {
+			a.recordAuthFailure(r)
+			a.log(r).Warn("Rejected unauthenticated request", "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="breeds"`)
+			writeProblem(w, http.StatusUnauthorized, "A valid API key or bearer token is required")
+			return
+		}
+		ctx := withIdentity(r.Context(), identity)
+		ctx = withLogger(ctx, a.log(r).With("subject", identity.Subject))
+		if info := requestInfoFromContext(ctx); info != nil {
+			info.subject = identity.Subject
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newAPIKeySecret returns a random secret, shown once to its owner, and its display prefix
+func newAPIKeySecret() (secret string, prefix string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return secret, secret[:len(apiKeyPrefix)+6], nil
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
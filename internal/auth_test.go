@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func signJWT(t *testing.T, alg, kid string, claims map[string]interface{}, sign func([]byte) []byte) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func hs256(input []byte) []byte {
+	mac := hmac.New(sha256.New, hmacSecret)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
+
+func TestJWKSVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := func(input []byte) []byte {
+		digest := sha256.Sum256(input)
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		return sig
+	}
+	jwks := &JWKS{Keys: []JWK{
+		{Kty: "oct", Kid: "hs", Alg: "HS256", K: base64.RawURLEncoding.EncodeToString(hmacSecret)},
+		{
+			Kty: "RSA", Kid: "rs", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+	}}
+	now := time.Now()
+	valid := map[string]interface{}{"sub": "alice", "exp": now.Add(time.Hour).Unix(), "iss": "japhy", "aud": []string{"breeds"}}
+	expired := map[string]interface{}{"sub": "alice", "exp": now.Add(-time.Hour).Unix()}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "HS256", token: signJWT(t, "HS256", "hs", valid, hs256)},
+		{name: "RS256", token: signJWT(t, "RS256", "rs", valid, rs256)},
+		{name: "RS256 without kid", token: signJWT(t, "RS256", "", valid, rs256)},
+		{name: "expired", token: signJWT(t, "HS256", "hs", expired, hs256), wantErr: true},
+		{name: "bad signature", token: signJWT(t, "HS256", "hs", valid, func([]byte) []byte { return []byte("nope") }), wantErr: true},
+		{name: "alg none", token: signJWT(t, "none", "", valid, func([]byte) []byte { return nil }), wantErr: true},
+		{name: "alg confusion", token: signJWT(t, "HS256", "rs", valid, hs256), wantErr: true},
+		{name: "unknown kid", token: signJWT(t, "HS256", "other", valid, hs256), wantErr: true},
+		{name: "malformed", token: "abc.def", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := jwks.Verify(tt.token, "japhy", "breeds", now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("err = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims["sub"] != "alice" {
+				t.Errorf("sub = %v", claims["sub"])
+			}
+		})
+	}
+
+	if _, err := jwks.Verify(signJWT(t, "HS256", "hs", valid, hs256), "other", "", now); err == nil {
+		t.Error("a token from another issuer should be rejected")
+	}
+}
+
+type requestFunc func(method, path string, header http.Header, body string) *httptest.ResponseRecorder
+
+// newAuthTestRouter serves an app requiring authentication, with "admin-secret" as admin token
+func newAuthTestRouter(t *testing.T) requestFunc {
+	t.Helper()
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.APIKeys = NewMemoryAPIKeyStore()
+	app.Roles = NewMemoryRoleStore()
+	app.AdminToken = "admin-secret"
+	app.Auth = &Authenticator{
+		Keys:  app.APIKeys,
+		Roles: app.Roles,
+		JWKS:  &JWKS{Keys: []JWK{{Kty: "oct", K: base64.RawURLEncoding.EncodeToString(hmacSecret)}}},
+	}
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	app.RegisterAdminRoutes(r.PathPrefix("/admin").Subrouter())
+
+	return func(method, path string, header http.Header, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+}
+
+// createTestAPIKey returns the secret of a new API key with role, none when empty
+func createTestAPIKey(t *testing.T, do requestFunc, role Role) string {
+	t.Helper()
+	rec := do(http.MethodPost, "/admin/api-keys", http.Header{"Authorization": {"Bearer admin-secret"}}, `{"name":"test","role":"`+string(role)+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create API key: status %d (%s)", rec.Code, rec.Body.String())
+	}
+	var created createdAPIKey
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	return created.Key
+}
+
+func TestAuthentication(t *testing.T) {
+	do := newAuthTestRouter(t)
+
+	if rec := do(http.MethodGet, "/v1/breeds", nil, ""); rec.Code != http.StatusUnauthorized || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("anonymous request: status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	admin := http.Header{"Authorization": {"Bearer admin-secret"}}
+	key := createTestAPIKey(t, do, "")
+
+	token := signJWT(t, "HS256", "", map[string]interface{}{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()}, hs256)
+	for name, header := range map[string]http.Header{
+		"X-API-Key":  {"X-Api-Key": {key}},
+		"bearer key": {"Authorization": {"Bearer " + key}},
+		"bearer JWT": {"Authorization": {"Bearer " + token}},
+	} {
+		if rec := do(http.MethodGet, "/v1/breeds", header, ""); rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d", name, rec.Code)
+		}
+	}
+
+	if rec := do(http.MethodDelete, "/admin/api-keys/1", admin, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke API key: status %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/v1/breeds", http.Header{"X-Api-Key": {key}}, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status %d, want 401", rec.Code)
+	}
+	checkProblem(t, do(http.MethodDelete, "/admin/api-keys/99", admin, ""), http.StatusNotFound)
+	checkProblem(t, do(http.MethodPost, "/admin/api-keys", admin, `{"name":""}`), http.StatusBadRequest)
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSConfig lists what browsers on other origins are allowed to do
+type CORSConfig struct {
+	// AllowedOrigins holds exact origins, "*" or wildcard subdomains such as "https://*.japhy.fr"
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// EnableCORS adds CORS headers to the responses of r and answers preflight requests before any other middleware
+func EnableCORS(r *mux.Router, cfg CORSConfig) {
+	r.Use(cfg.middleware)
+	// preflight requests must match a route for the middleware to run
+	r.Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (cfg CORSConfig) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !cfg.originAllowed(origin) {
+			if preflight {
+				writeProblem(w, http.StatusForbidden, "Origin not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if preflight && !containsFold(cfg.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+			writeProblem(w, http.StatusForbidden, "Method not allowed by CORS policy")
+			return
+		}
+
+		if cfg.allowsAnyOrigin() && !cfg.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(cfg.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+		if containsFold(cfg.AllowedHeaders, "*") {
+			w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		} else if len(cfg.AllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+		}
+		if cfg.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (cfg CORSConfig) allowsAnyOrigin() bool {
+	return containsFold(cfg.AllowedOrigins, "*")
+}
+
+func (cfg CORSConfig) originAllowed(origin string) bool {
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		scheme, host, found := strings.Cut(allowed, "://*.")
+		if found && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityConfig sets the security headers added to every response
+type SecurityConfig struct {
+	ContentSecurityPolicy string
+	// HSTSMaxAge enables Strict-Transport-Security when not zero, only for deployments served over HTTPS
+	HSTSMaxAge time.Duration
+}
+
+// SecurityHeaders adds the headers hardening browsers against sniffing, framing and referrer leaks
+func SecurityHeaders(cfg SecurityConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "no-referrer")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+			if cfg.ContentSecurityPolicy != "" {
+				h.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+			}
+			if cfg.HSTSMaxAge > 0 {
+				h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))+"; includeSubDomains")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func newCORSTestRouter(cfg CORSConfig) *mux.Router {
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.APIKeys = NewMemoryAPIKeyStore()
+	app.Roles = NewMemoryRoleStore()
+	app.Auth = &Authenticator{Keys: app.APIKeys, Roles: app.Roles}
+
+	r := mux.NewRouter()
+	r.Use(SecurityHeaders(SecurityConfig{ContentSecurityPolicy: "default-src 'none'", HSTSMaxAge: time.Hour}))
+	v1 := r.PathPrefix("/v1").Subrouter()
+	EnableCORS(v1, cfg)
+	app.RegisterRoutes(v1)
+	return r
+}
+
+func TestCORS(t *testing.T) {
+	r := newCORSTestRouter(CORSConfig{
+		AllowedOrigins:   []string{"https://backoffice.japhy.fr", "https://*.preview.japhy.fr"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		origin        string
+		requestMethod string
+		wantStatus    int
+		wantOrigin    string
+		wantHeaders   map[string]string
+	}{
+		{
+			name: "preflight", method: http.MethodOptions, origin: "https://backoffice.japhy.fr", requestMethod: "DELETE",
+			wantStatus: http.StatusNoContent, wantOrigin: "https://backoffice.japhy.fr",
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods":     "GET, POST, DELETE",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{name: "preflight wildcard subdomain", method: http.MethodOptions, origin: "https://pr-12.preview.japhy.fr", requestMethod: "GET", wantStatus: http.StatusNoContent, wantOrigin: "https://pr-12.preview.japhy.fr"},
+		{name: "preflight unknown origin", method: http.MethodOptions, origin: "https://evil.example", requestMethod: "GET", wantStatus: http.StatusForbidden},
+		{name: "preflight method not allowed", method: http.MethodOptions, origin: "https://backoffice.japhy.fr", requestMethod: "PUT", wantStatus: http.StatusForbidden},
+		{
+			name: "actual request", method: http.MethodGet, origin: "https://backoffice.japhy.fr",
+			wantStatus: http.StatusUnauthorized, wantOrigin: "https://backoffice.japhy.fr",
+			wantHeaders: map[string]string{"Access-Control-Expose-Headers": "RateLimit-Remaining"},
+		},
+		{name: "actual request unknown origin", method: http.MethodGet, origin: "https://evil.example", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/breeds/1", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			for key, want := range tt.wantHeaders {
+				if got := rec.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	r := newCORSTestRouter(CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+	req := httptest.NewRequest(http.MethodOptions, "/v1/breeds", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("status %d, Access-Control-Allow-Origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	r := newCORSTestRouter(CORSConfig{})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds", nil))
+
+	for key, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "default-src 'none'",
+		"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+	} {
+		if got := rec.Header().Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// OpenAPISpec is the OpenAPI 3.1 document of the routes registered by RegisterRoutes
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// docsCSP lets the bundled Swagger UI load its own scripts and styles, the API keeps the strict policy
+const docsCSP = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'"
+
+// swaggerInitializer replaces the one of the Swagger UI distribution, which loads the petstore example
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// RegisterDocs serves the OpenAPI document at /openapi.json and Swagger UI at /docs/, both public
+func RegisterDocs(r *mux.Router) {
+	r.HandleFunc("/openapi.json", ServeOpenAPISpec).Methods(http.MethodGet)
+	r.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently)).Methods(http.MethodGet)
+
+	docs := r.PathPrefix("/docs/").Subrouter()
+	docs.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Security-Policy", docsCSP)
+			next.ServeHTTP(w, r)
+		})
+	})
+	docs.HandleFunc("/swagger-initializer.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write([]byte(swaggerInitializer))
+	}).Methods(http.MethodGet)
+	docs.PathPrefix("/").Handler(http.StripPrefix("/docs/", http.FileServer(http.FS(swaggerFiles.FS)))).Methods(http.MethodGet)
+}
+
+func ServeOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPISpec)
+}
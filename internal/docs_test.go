@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.1") {
+		t.Errorf("openapi = %q, want 3.1", spec.OpenAPI)
+	}
+
+	app := NewApp(charmLog.New(io.Discard))
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+
+	routes := 0
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		path := routeVariablePattern.ReplaceAllString(strings.TrimPrefix(template, "/v1"), "{$1}")
+		for _, method := range methods {
+			routes++
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is missing from openapi.json", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes == 0 {
+		t.Fatal("no route registered")
+	}
+}
+
+func TestDocs(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterDocs(r)
+
+	tests := []struct {
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{path: "/openapi.json", wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `"openapi": "3.1.0"`},
+		{path: "/docs", wantStatus: http.StatusMovedPermanently},
+		{path: "/docs/", wantStatus: http.StatusOK, wantContentType: "text/html", wantBody: "swagger-ui"},
+		{path: "/docs/swagger-initializer.js", wantStatus: http.StatusOK, wantContentType: "text/javascript", wantBody: `url: "/openapi.json"`},
+		{path: "/docs/swagger-ui-bundle.js", wantStatus: http.StatusOK, wantContentType: "text/javascript"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantContentType) {
+				t.Errorf("content type %q, want %q", rec.Header().Get("Content-Type"), tt.wantContentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body lacks %q", tt.wantBody)
+			}
+		})
+	}
+}
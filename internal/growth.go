@@ -0,0 +1,318 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GrowthCurve describes how the pets of a size category, or of a breed, reach their adult weight
+//
+// The expected weight follows a Gompertz curve starting at BirthShare of the adult weight and reaching 98% of it
+// at AdultWeeks, the band around it being ±Spread
+type GrowthCurve struct {
+	Species    string  `json:"species,omitempty"`
+	PetSize    string  `json:"pet_size,omitempty"`
+	BreedID    int     `json:"breed_id,omitempty"`
+	BirthShare float64 `json:"birth_share"`
+	AdultWeeks int     `json:"adult_weeks"`
+	Spread     float64 `json:"spread"`
+	// Source is where the curve comes from in the answers: breed, category or default
+	Source string `json:"source,omitempty"`
+}
+
+// defaultGrowthCurves are used for the size categories of DefaultSizeClasses without a stored curve
+var defaultGrowthCurves = []GrowthCurve{
+	{Species: "dog", PetSize: "toy", BirthShare: 0.06, AdultWeeks: 36, Spread: 0.1},
+	{Species: "dog", PetSize: "small", BirthShare: 0.05, AdultWeeks: 40, Spread: 0.1},
+	{Species: "dog", PetSize: "medium", BirthShare: 0.03, AdultWeeks: 52, Spread: 0.1},
+	{Species: "dog", PetSize: "large", BirthShare: 0.02, AdultWeeks: 65, Spread: 0.12},
+	{Species: "dog", PetSize: "giant", BirthShare: 0.012, AdultWeeks: 90, Spread: 0.12},
+	{Species: "cat", PetSize: "small", BirthShare: 0.035, AdultWeeks: 48, Spread: 0.1},
+	{Species: "cat", PetSize: "medium", BirthShare: 0.03, AdultWeeks: 52, Spread: 0.1},
+	{Species: "cat", PetSize: "large", BirthShare: 0.025, AdultWeeks: 78, Spread: 0.12},
+}
+
+// fallbackPetSize is the category of the breeds whose size has no curve, as those of species without size classes
+const fallbackPetSize = "medium"
+
+// adultShare is the share of the adult weight reached at AdultWeeks
+const adultShare = 0.98
+
+func (c GrowthCurve) validate() error {
+	switch {
+	case c.BirthShare <= 0 || c.BirthShare > 0.5:
+		return errors.New("birth_share must be above 0 and at most 0.5")
+	case c.AdultWeeks < 1:
+		return errors.New("adult_weeks must be a positive number of weeks")
+	case c.Spread < 0 || c.Spread >= 1:
+		return errors.New("spread must be at least 0 and below 1")
+	}
+	return nil
+}
+
+// Share returns the share of the adult weight expected at ageWeeks
+func (c GrowthCurve) Share(ageWeeks float64) float64 {
+	b := -math.Log(c.BirthShare)
+	rate := math.Log(b/-math.Log(adultShare)) / float64(c.AdultWeeks)
+	return math.Exp(-b * math.Exp(-rate*ageWeeks))
+}
+
+// GrowthEstimate is the answer of GetBreedGrowth, weights are in grams
+type GrowthEstimate struct {
+	BreedID     int         `json:"breed_id"`
+	Species     string      `json:"species"`
+	Sex         Sex         `json:"sex,omitempty"`
+	AgeWeeks    int         `json:"age_weeks"`
+	AdultWeight float64     `json:"adult_weight"`
+	Weight      float64     `json:"weight"`
+	WeightMin   float64     `json:"weight_min"`
+	WeightMax   float64     `json:"weight_max"`
+	Curve       GrowthCurve `json:"curve"`
+}
+
+// growthCurve returns the curve of breed: its own, else the stored one of its size category, else the default one
+func (a *App) growthCurve(ctx context.Context, breed Breed) (GrowthCurve, error) {
+	curve, err := a.Growth.GetBreed(ctx, breed.ID)
+	if err == nil {
+		curve.Source = "breed"
+		return curve, nil
+	}
+	if !errors.Is(err, ErrGrowthCurveNotFound) {
+		return GrowthCurve{}, err
+	}
+
+	sizes := []string{breed.PetSize, fallbackPetSize}
+	for _, size := range sizes {
+		curve, err := a.Growth.Get(ctx, breed.Species, size)
+		if err == nil {
+			curve.Source = "category"
+			return curve, nil
+		}
+		if !errors.Is(err, ErrGrowthCurveNotFound) {
+			return GrowthCurve{}, err
+		}
+		for _, curve := range defaultGrowthCurves {
+			if curve.Species == breed.Species && curve.PetSize == size {
+				curve.Source = "default"
+				return curve, nil
+			}
+		}
+	}
+	return GrowthCurve{}, ErrGrowthCurveNotFound
+}
+
+// GetBreedGrowth returns the weight band expected at `age_weeks` for the breed, of `sex` or both sexes averaged
+func (a *App) GetBreedGrowth(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	query := r.URL.Query()
+	age, err := strconv.Atoi(query.Get("age_weeks"))
+	if err != nil || age < 0 {
+		writeProblem(w, http.StatusBadRequest, "age_weeks must be a number of weeks")
+		return
+	}
+	sex := Sex(query.Get("sex"))
+	if sex != "" && sex != SexMale && sex != SexFemale {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("sex must be %s or %s", SexMale, SexFemale))
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.growth")
+	defer cancel()
+	breed, ok := a.routeBreed(w, r, ctx, id)
+	if !ok {
+		return
+	}
+	curve, err := a.growthCurve(ctx, breed)
+	if errors.Is(err, ErrGrowthCurveNotFound) {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("No growth curve for %s breeds", breed.Species))
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch growth curve", "breed_id", id)
+		return
+	}
+
+	adult := breed.AdultWeight(sex)
+	weight := adult * curve.Share(float64(age))
+	estimate := GrowthEstimate{
+		BreedID:     id,
+		Species:     breed.Species,
+		Sex:         sex,
+		AgeWeeks:    age,
+		AdultWeight: adult,
+		Weight:      math.Round(weight),
+		WeightMin:   math.Round(weight * (1 - curve.Spread)),
+		WeightMax:   math.Round(weight * (1 + curve.Spread)),
+		Curve:       curve,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estimate)
+}
+
+// ListGrowthCurves returns the curve of every size category, stored or default, by species and size
+func (a *App) ListGrowthCurves(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "growth_curves.list")
+	defer cancel()
+	stored, err := a.Growth.List(ctx)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch growth curves")
+		return
+	}
+
+	curves := []GrowthCurve{}
+	seen := map[[2]string]bool{}
+	for _, curve := range stored {
+		curve.Source = "category"
+		curves = append(curves, curve)
+		seen[[2]string{curve.Species, curve.PetSize}] = true
+	}
+	for _, curve := range defaultGrowthCurves {
+		if !seen[[2]string{curve.Species, curve.PetSize}] {
+			curve.Source = "default"
+			curves = append(curves, curve)
+		}
+	}
+	sort.Slice(curves, func(i, j int) bool {
+		if curves[i].Species != curves[j].Species {
+			return curves[i].Species < curves[j].Species
+		}
+		return curves[i].PetSize < curves[j].PetSize
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curves)
+}
+
+// SetGrowthCurve replaces the curve of the size category `pet_size` of `species`
+func (a *App) SetGrowthCurve(w http.ResponseWriter, r *http.Request) {
+	curve, ok := a.decodeGrowthCurve(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	curve.Species, curve.PetSize = vars["species"], vars["pet_size"]
+
+	ctx, cancel := a.queryContext(r, "growth_curves.set")
+	defer cancel()
+	if err := a.Growth.Set(ctx, curve); err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to store growth curve", "species", curve.Species, "pet_size", curve.PetSize)
+		return
+	}
+
+	curve.Source = "category"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curve)
+}
+
+// GetBreedGrowthCurve returns the curve used for the breed, its own or the one of its size category
+func (a *App) GetBreedGrowthCurve(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "growth_curves.get_breed")
+	defer cancel()
+	breed, ok := a.routeBreed(w, r, ctx, id)
+	if !ok {
+		return
+	}
+	curve, err := a.growthCurve(ctx, breed)
+	if errors.Is(err, ErrGrowthCurveNotFound) {
+		writeProblem(w, http.StatusNotFound, "Growth curve not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch growth curve", "breed_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curve)
+}
+
+// SetBreedGrowthCurve overrides the curve of the size category of the breed
+func (a *App) SetBreedGrowthCurve(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	curve, ok := a.decodeGrowthCurve(w, r)
+	if !ok {
+		return
+	}
+	curve.BreedID = id
+
+	ctx, cancel := a.queryContext(r, "growth_curves.set_breed")
+	defer cancel()
+	if _, ok := a.routeBreed(w, r, ctx, id); !ok {
+		return
+	}
+	if err := a.Growth.SetBreed(ctx, curve); err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to store growth curve", "breed_id", id)
+		return
+	}
+
+	curve.Source = "breed"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curve)
+}
+
+// DeleteBreedGrowthCurve removes the curve of the breed, which goes back to the one of its size category
+func (a *App) DeleteBreedGrowthCurve(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "growth_curves.delete_breed")
+	defer cancel()
+	if _, ok := a.routeBreed(w, r, ctx, id); !ok {
+		return
+	}
+	err := a.Growth.DeleteBreed(ctx, id)
+	if errors.Is(err, ErrGrowthCurveNotFound) {
+		writeProblem(w, http.StatusNotFound, "Growth curve not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to delete growth curve", "breed_id", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeBreed fetches the breed of a /breeds/{id} route, answering 404 when it does not exist
+func (a *App) routeBreed(w http.ResponseWriter, r *http.Request, ctx context.Context, id int) (Breed, bool) {
+	breed, err := a.Store.Get(ctx, id)
+	if errors.Is(err, ErrBreedNotFound) {
+		writeProblem(w, http.StatusNotFound, "Breed not found")
+		return Breed{}, false
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch breed", "breed_id", id)
+		return Breed{}, false
+	}
+	return breed, true
+}
+
+// decodeGrowthCurve reads and validates the curve parameters of the request body, answering 400 when invalid
+func (a *App) decodeGrowthCurve(w http.ResponseWriter, r *http.Request) (GrowthCurve, bool) {
+	var body struct {
+		BirthShare float64 `json:"birth_share"`
+		AdultWeeks int     `json:"adult_weeks"`
+		Spread     float64 `json:"spread"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return GrowthCurve{}, false
+	}
+	curve := GrowthCurve{BirthShare: body.BirthShare, AdultWeeks: body.AdultWeeks, Spread: body.Spread}
+	if err := curve.validate(); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return GrowthCurve{}, false
+	}
+	return curve, true
+}
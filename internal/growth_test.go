@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func newGrowthTestRouter(t *testing.T, logs *bytes.Buffer) *mux.Router {
+	t.Helper()
+	validator, err := NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	app := NewApp(charmLog.New(logs))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.Growth = NewMemoryGrowthCurveStore()
+	app.Validator = validator
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	return r
+}
+
+func TestGrowthCurveShare(t *testing.T) {
+	curve := GrowthCurve{BirthShare: 0.03, AdultWeeks: 52, Spread: 0.1}
+	if share := curve.Share(0); math.Abs(share-0.03) > 1e-9 {
+		t.Errorf("Share(0) = %v, want 0.03", share)
+	}
+	if share := curve.Share(52); math.Abs(share-adultShare) > 1e-9 {
+		t.Errorf("Share(52) = %v, want %v", share, adultShare)
+	}
+	previous := 0.0
+	for week := 0; week <= 104; week++ {
+		share := curve.Share(float64(week))
+		if share <= previous || share >= 1 {
+			t.Fatalf("Share(%d) = %v after %v, want an increasing share below 1", week, share, previous)
+		}
+		previous = share
+	}
+}
+
+func TestGrowthHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "birth", method: http.MethodGet, path: "/v1/breeds/1/growth?age_weeks=0&sex=male", wantStatus: http.StatusOK, wantBody: `"weight":300,"weight_min":270,"weight_max":330`},
+		{name: "adult age", method: http.MethodGet, path: "/v1/breeds/1/growth?age_weeks=40&sex=male", wantStatus: http.StatusOK, wantBody: `"weight":5880`},
+		{name: "large breed", method: http.MethodGet, path: "/v1/breeds/2/growth?age_weeks=65&sex=female", wantStatus: http.StatusOK, wantBody: `"weight":34300`},
+		{name: "cat", method: http.MethodGet, path: "/v1/breeds/3/growth?age_weeks=12", wantStatus: http.StatusOK, wantBody: `"species":"cat","pet_size":"medium"`},
+		{name: "without age", method: http.MethodGet, path: "/v1/breeds/1/growth", wantStatus: http.StatusBadRequest},
+		{name: "invalid sex", method: http.MethodGet, path: "/v1/breeds/1/growth?age_weeks=4&sex=other", wantStatus: http.StatusBadRequest},
+		{name: "unknown breed", method: http.MethodGet, path: "/v1/breeds/42/growth?age_weeks=4", wantStatus: http.StatusNotFound},
+		{name: "list", method: http.MethodGet, path: "/v1/growth-curves", wantStatus: http.StatusOK, wantBody: `"species":"dog","pet_size":"giant"`},
+		{name: "breed curve", method: http.MethodGet, path: "/v1/breeds/1/growth-curve", wantStatus: http.StatusOK, wantBody: `"source":"default"`},
+		{name: "set breed curve", method: http.MethodPut, path: "/v1/breeds/1/growth-curve", body: `{"birth_share":0.04,"adult_weeks":36,"spread":0.15}`, wantStatus: http.StatusOK, wantBody: `"breed_id":1`},
+		{name: "set curve of unknown breed", method: http.MethodPut, path: "/v1/breeds/42/growth-curve", body: `{"birth_share":0.04,"adult_weeks":36,"spread":0.15}`, wantStatus: http.StatusNotFound},
+		{name: "set invalid curve", method: http.MethodPut, path: "/v1/breeds/1/growth-curve", body: `{"birth_share":0,"adult_weeks":36,"spread":0.15}`, wantStatus: http.StatusBadRequest},
+		{name: "delete missing breed curve", method: http.MethodDelete, path: "/v1/breeds/1/growth-curve", wantStatus: http.StatusNotFound, wantBody: "Growth curve not found"},
+		{name: "delete curve of unknown breed", method: http.MethodDelete, path: "/v1/breeds/42/growth-curve", wantStatus: http.StatusNotFound, wantBody: "Breed not found"},
+		{name: "set category curve", method: http.MethodPut, path: "/v1/growth-curves/dog/large", body: `{"birth_share":0.015,"adult_weeks":70,"spread":0.1}`, wantStatus: http.StatusOK, wantBody: `"source":"category"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			r := newGrowthTestRouter(t, &logs)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %s lacks %s", rec.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(logs.String(), "Response does not match") {
+				t.Errorf("response flagged as not matching the spec: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestGrowthCurveOverrides(t *testing.T) {
+	var logs bytes.Buffer
+	r := newGrowthTestRouter(t, &logs)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	source := func() string {
+		rec := send(http.MethodGet, "/v1/breeds/2/growth?age_weeks=10", "")
+		var got GrowthEstimate
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%v (body: %s)", err, rec.Body.String())
+		}
+		return got.Curve.Source
+	}
+
+	if got := source(); got != "default" {
+		t.Errorf("source = %s, want default", got)
+	}
+	if rec := send(http.MethodPut, "/v1/growth-curves/dog/large", `{"birth_share":0.015,"adult_weeks":70,"spread":0.1}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+	if got := source(); got != "category" {
+		t.Errorf("source after setting the category = %s, want category", got)
+	}
+	if rec := send(http.MethodPut, "/v1/breeds/2/growth-curve", `{"birth_share":0.02,"adult_weeks":80,"spread":0.1}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+	if got := source(); got != "breed" {
+		t.Errorf("source after setting the breed = %s, want breed", got)
+	}
+	if rec := send(http.MethodDelete, "/v1/breeds/2/growth-curve", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+	if got := source(); got != "category" {
+		t.Errorf("source after deleting the breed curve = %s, want category", got)
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtLeeway tolerates clock skew between the token issuer and the API
+const jwtLeeway = 30 * time.Second
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWK is a key of a JSON Web Key Set, either "oct" (HS256) or "RSA" (RS256)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS holds the keys JWT bearer tokens are verified against
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadJWKS reads a JSON Web Key Set file
+func LoadJWKS(path string) (*JWKS, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read JWKS file %s: %w", path, err)
+	}
+	var jwks JWKS
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+	return &jwks, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// JWTClaims are the claims of a verified token
+type JWTClaims map[string]interface{}
+
+// Verify checks the signature of an HS256 or RS256 token and its exp, nbf, iss and aud claims
+//
+// issuer and audience are only checked when not empty
+func (j *JWKS) Verify(token, issuer, audience string, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	key, err := j.key(header)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.verify(header.Alg, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := claims.validate(issuer, audience, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key matching the token kid, or the only key usable for its alg when there is no kid
+func (j *JWKS) key(header jwtHeader) (*JWK, error) {
+	kty := map[string]string{"HS256": "oct", "RS256": "RSA"}[header.Alg]
+	if kty == "" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+
+	var found *JWK
+	for i, key := range j.Keys {
+		if key.Kty != kty || (key.Alg != "" && key.Alg != header.Alg) {
+			continue
+		}
+		if header.Kid != "" && key.Kid == header.Kid {
+			return &j.Keys[i], nil
+		}
+		if header.Kid == "" {
+			if found != nil {
+				return nil, fmt.Errorf("%w: kid required", ErrInvalidToken)
+			}
+			found = &j.Keys[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidToken)
+	}
+	return found, nil
+}
+
+func (k *JWK) verify(alg string, signingInput, signature []byte) error {
+	switch alg {
+	case "HS256":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return fmt.Errorf("%w: invalid key %s", ErrInvalidToken, k.Kid)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	case "RS256":
+		publicKey, err := k.rsaPublicKey()
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(signingInput)
+		if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+}
+
+func (k *JWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key %s", ErrInvalidToken, k.Kid)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("%w: invalid key %s", ErrInvalidToken, k.Kid)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func (c JWTClaims) validate(issuer, audience string, now time.Time) error {
+	if _, ok := c["sub"].(string); !ok {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	exp, ok := c["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.Add(-jwtLeeway).After(time.Unix(int64(exp), 0)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := c["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if issuer != "" && c["iss"] != issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if audience != "" && !c.hasAudience(audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+// hasAudience handles aud as a single string or as an array
+func (c JWTClaims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	body, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID bounds the request ids accepted from clients, which end up in the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestInfo is filled while the request goes down the router, for LogRequests to log once it is served
+type requestInfo struct {
+	route   string
+	subject string
+}
+
+// LoggerFromContext returns the request-scoped logger set by LogRequests, or nil
+func LoggerFromContext(ctx context.Context) *charmLog.Logger {
+	logger, _ := ctx.Value(loggerKey).(*charmLog.Logger)
+	return logger
+}
+
+func withLogger(ctx context.Context, logger *charmLog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey).(*requestInfo)
+	return info
+}
+
+// log returns the logger of the request, tagged with its request id
+func (a *App) log(r *http.Request) *charmLog.Logger {
+	if logger := LoggerFromContext(r.Context()); logger != nil {
+		return logger
+	}
+	return a.logger
+}
+
+// LogRequests wraps the whole router: it propagates or assigns the X-Request-ID, injects the request-scoped
+// logger in the context and logs every request once served
+//
+// Routes are logged and recorded in Metrics by template (see CaptureRoute), "unmatched" for 404 and 405 answers
+func (a *App) LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		logger := a.logger.With("request_id", requestID)
+		info := &requestInfo{route: "unmatched"}
+		ctx := withLogger(r.Context(), logger)
+		ctx = context.WithValue(ctx, requestInfoKey, info)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		latency := time.Since(start)
+		if a.Metrics != nil {
+			a.Metrics.observeRequest(r.Method, info.route, rec.status, latency)
+		}
+
+		fields := []interface{}{
+			"method", r.Method,
+			"route", info.route,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"latency_ms", float64(latency.Microseconds()) / 1000,
+			"remote", r.RemoteAddr,
+		}
+		if info.subject != "" {
+			fields = append(fields, "subject", info.subject)
+		}
+		switch {
+		case rec.status >= http.StatusInternalServerError:
+			logger.Error("Request served", fields...)
+		case rec.status >= http.StatusBadRequest:
+			logger.Warn("Request served", fields...)
+		default:
+			logger.Info("Request served", fields...)
+		}
+	})
+}
+
+// CaptureRoute records the template of the matched route for LogRequests, it must be used on the root router
+func CaptureRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := requestInfoFromContext(r.Context()); info != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					info.route = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// statusRecorder captures the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
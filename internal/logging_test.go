@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func TestLogRequests(t *testing.T) {
+	var logs bytes.Buffer
+	app := NewApp(charmLog.NewWithOptions(&logs, charmLog.Options{Formatter: charmLog.JSONFormatter}))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	r := mux.NewRouter()
+	r.Use(CaptureRoute)
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	handler := app.LogRequests(r)
+
+	tests := []struct {
+		name          string
+		path          string
+		requestID     string
+		wantRequestID string
+		wantRoute     string
+		wantStatus    float64
+		wantLines     int
+	}{
+		{name: "propagated id", path: "/v1/breeds/1", requestID: "abc-123", wantRequestID: "abc-123", wantRoute: "/v1/breeds/{id:[0-9]+}", wantStatus: 200, wantLines: 1},
+		{name: "handler logs", path: "/v1/breeds/42", requestID: "def-456", wantRequestID: "def-456", wantRoute: "/v1/breeds/{id:[0-9]+}", wantStatus: 404, wantLines: 2},
+		{name: "invalid id replaced", path: "/v1/breeds", requestID: "bad id\n", wantRoute: "/v1/breeds", wantStatus: 200, wantLines: 1},
+		{name: "unmatched", path: "/v2/nothing", wantRoute: "unmatched", wantStatus: 404, wantLines: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(RequestIDHeader)
+			if tt.wantRequestID != "" && requestID != tt.wantRequestID {
+				t.Errorf("%s = %q, want %q", RequestIDHeader, requestID, tt.wantRequestID)
+			}
+			if !validRequestID.MatchString(requestID) {
+				t.Errorf("invalid generated request id %q", requestID)
+			}
+
+			lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("got %d log lines, want %d: %s", len(lines), tt.wantLines, logs.String())
+			}
+			for _, line := range lines {
+				var entry map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("invalid JSON log line %q", line)
+				}
+				if entry["request_id"] != requestID {
+					t.Errorf("request_id = %v, want %q", entry["request_id"], requestID)
+				}
+			}
+
+			var access map[string]interface{}
+			json.Unmarshal([]byte(lines[len(lines)-1]), &access)
+			if access["route"] != tt.wantRoute || access["status"] != tt.wantStatus || access["method"] != "GET" {
+				t.Errorf("access log = %v", access)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+)
+
+type memoryBreedStore struct {
+	mu     sync.RWMutex
+	breeds map[int]Breed
+	nextID int
+	// deleted are called with the id of each deleted breed, as SQL foreign keys would act
+	deleted []func(id int)
+}
+
+// NewMemoryBreedStore returns a thread-safe BreedStore seeded with records, ids start at 1 in records order
+func NewMemoryBreedStore(records []database_actions.BreedRecord) BreedStore {
+	s := &memoryBreedStore{
+		breeds: make(map[int]Breed, len(records)),
+		nextID: 1,
+	}
+	for _, record := range records {
+		s.insert(Breed{
+			Name:      record.Name,
+			Species:   record.Species,
+			PetSize:   record.PetSize,
+			WeightMin: record.WeightMin,
+			WeightMax: record.WeightMax,
+		})
+	}
+	return s
+}
+
+func (s *memoryBreedStore) List(ctx context.Context) ([]Breed, error) {
+	return s.Search(ctx, BreedFilter{})
+}
+
+func (s *memoryBreedStore) Get(ctx context.Context, id int) (Breed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	breed, ok := s.breeds[id]
+	if !ok {
+		return Breed{}, ErrBreedNotFound
+	}
+	return breed, nil
+}
+
+func (s *memoryBreedStore) Search(ctx context.Context, filter BreedFilter) ([]Breed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var breeds []Breed
+	for _, breed := range s.breeds {
+		if filter.match(breed) {
+			breeds = append(breeds, breed)
+		}
+	}
+	sort.Slice(breeds, func(i, j int) bool { return breeds[i].ID < breeds[j].ID })
+	if filter.Limit > 0 && len(breeds) > filter.Limit {
+		breeds = breeds[:filter.Limit]
+	}
+	return breeds, nil
+}
+
+func (s *memoryBreedStore) Create(ctx context.Context, breed Breed) (Breed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insert(breed), nil
+}
+
+func (s *memoryBreedStore) Update(ctx context.Context, breed Breed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.breeds[breed.ID]; ok {
+		s.breeds[breed.ID] = withAverageWeight(breed)
+	}
+	return nil
+}
+
+func (s *memoryBreedStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	delete(s.breeds, id)
+	deleted := s.deleted
+	s.mu.Unlock()
+
+	for _, fn := range deleted {
+		fn(id)
+	}
+	return nil
+}
+
+// onDelete calls fn with the id of each breed deleted from now on
+func (s *memoryBreedStore) onDelete(fn func(id int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleted = append(s.deleted, fn)
+}
+
+// insert must be called with the write lock held
+func (s *memoryBreedStore) insert(breed Breed) Breed {
+	breed.ID = s.nextID
+	s.nextID++
+	breed = withAverageWeight(breed)
+	s.breeds[breed.ID] = breed
+	return breed
+}
+
+func withAverageWeight(breed Breed) Breed {
+	breed.AverageWeight = (breed.WeightMin + breed.WeightMax) / 2
+	return breed
+}
+
+type memoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys []APIKey
+}
+
+// NewMemoryAPIKeyStore returns an empty thread-safe APIKeyStore
+func NewMemoryAPIKeyStore() APIKeyStore {
+	return &memoryAPIKeyStore{}
+}
+
+func (s *memoryAPIKeyStore) Create(ctx context.Context, key APIKey) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.ID = len(s.keys) + 1
+	s.keys = append(s.keys, key)
+	return key, nil
+}
+
+func (s *memoryAPIKeyStore) List(ctx context.Context) ([]APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]APIKey{}, s.keys...), nil
+}
+
+func (s *memoryAPIKeyStore) FindByHash(ctx context.Context, hash string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrAPIKeyNotFound
+}
+
+func (s *memoryAPIKeyStore) Revoke(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > len(s.keys) {
+		return ErrAPIKeyNotFound
+	}
+	if s.keys[id-1].RevokedAt == nil {
+		s.keys[id-1].RevokedAt = &at
+	}
+	return nil
+}
+
+type memoryRoleStore struct {
+	mu          sync.RWMutex
+	assignments map[string]RoleAssignment
+}
+
+// NewMemoryRoleStore returns an empty thread-safe RoleStore
+func NewMemoryRoleStore() RoleStore {
+	return &memoryRoleStore{assignments: map[string]RoleAssignment{}}
+}
+
+func (s *memoryRoleStore) List(ctx context.Context) ([]RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	assignments := make([]RoleAssignment, 0, len(s.assignments))
+	for _, assignment := range s.assignments {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].Subject < assignments[j].Subject })
+	return assignments, nil
+}
+
+func (s *memoryRoleStore) Get(ctx context.Context, subject string) (RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	assignment, ok := s.assignments[subject]
+	if !ok {
+		return RoleAssignment{}, ErrRoleNotAssigned
+	}
+	return assignment, nil
+}
+
+func (s *memoryRoleStore) Assign(ctx context.Context, assignment RoleAssignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.assignments[assignment.Subject] = assignment
+	return nil
+}
+
+func (s *memoryRoleStore) Unassign(ctx context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.assignments[subject]; !ok {
+		return ErrRoleNotAssigned
+	}
+	delete(s.assignments, subject)
+	return nil
+}
+
+type memoryPetStore struct {
+	mu     sync.RWMutex
+	pets   map[int]Pet
+	nextID int
+}
+
+// NewMemoryPetStore returns an empty thread-safe PetStore
+//
+// Like the SQL store, it drops the breed_id and the share of a breed deleted from breeds,
+// which must come from NewMemoryBreedStore for that
+func NewMemoryPetStore(breeds BreedStore) PetStore {
+	s := &memoryPetStore{pets: map[int]Pet{}, nextID: 1}
+	if breeds, ok := breeds.(*memoryBreedStore); ok {
+		breeds.onDelete(s.breedDeleted)
+	}
+	return s
+}
+
+// breedDeleted clears the references to the breed id, as ON DELETE SET NULL and CASCADE do on SQL
+func (s *memoryPetStore) breedDeleted(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for petID, pet := range s.pets {
+		if pet.BreedID != nil && *pet.BreedID == id {
+			pet.BreedID = nil
+		}
+		breeds := pet.Breeds[:0:0]
+		for _, share := range pet.Breeds {
+			if share.BreedID != id {
+				breeds = append(breeds, share)
+			}
+		}
+		pet.Breeds = breeds
+		s.pets[petID] = pet
+	}
+}
+
+func (s *memoryPetStore) List(ctx context.Context) ([]Pet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pets := make([]Pet, 0, len(s.pets))
+	for _, pet := range s.pets {
+		pets = append(pets, pet)
+	}
+	sort.Slice(pets, func(i, j int) bool { return pets[i].ID < pets[j].ID })
+	return pets, nil
+}
+
+func (s *memoryPetStore) Get(ctx context.Context, id int) (Pet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pet, ok := s.pets[id]
+	if !ok {
+		return Pet{}, ErrPetNotFound
+	}
+	return pet, nil
+}
+
+func (s *memoryPetStore) Create(ctx context.Context, pet Pet) (Pet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pet.ID = s.nextID
+	s.nextID++
+	pet.Breeds = append([]BreedShare(nil), pet.Breeds...)
+	s.pets[pet.ID] = pet
+	return pet, nil
+}
+
+func (s *memoryPetStore) Update(ctx context.Context, pet Pet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pets[pet.ID]; !ok {
+		return ErrPetNotFound
+	}
+	pet.Breeds = append([]BreedShare(nil), pet.Breeds...)
+	s.pets[pet.ID] = pet
+	return nil
+}
+
+func (s *memoryPetStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pets[id]; !ok {
+		return ErrPetNotFound
+	}
+	delete(s.pets, id)
+	return nil
+}
+
+type memoryProductStore struct {
+	mu       sync.RWMutex
+	products map[int]Product
+	nextID   int
+}
+
+// NewMemoryProductStore returns an empty thread-safe ProductStore
+func NewMemoryProductStore() ProductStore {
+	return &memoryProductStore{products: map[int]Product{}, nextID: 1}
+}
+
+func (s *memoryProductStore) List(ctx context.Context, filter ProductFilter) ([]Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	products := []Product{}
+	for _, product := range s.products {
+		if filter.Species == "" || product.Species == filter.Species {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+func (s *memoryProductStore) Get(ctx context.Context, id int) (Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	product, ok := s.products[id]
+	if !ok {
+		return Product{}, ErrProductNotFound
+	}
+	return product, nil
+}
+
+func (s *memoryProductStore) Create(ctx context.Context, product Product) (Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product.ID = s.nextID
+	s.nextID++
+	s.products[product.ID] = product.withEmptyLists()
+	return s.products[product.ID], nil
+}
+
+func (s *memoryProductStore) Update(ctx context.Context, product Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[product.ID]; !ok {
+		return ErrProductNotFound
+	}
+	s.products[product.ID] = product.withEmptyLists()
+	return nil
+}
+
+func (s *memoryProductStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[id]; !ok {
+		return ErrProductNotFound
+	}
+	delete(s.products, id)
+	return nil
+}
+
+type memoryGrowthCurveStore struct {
+	mu         sync.RWMutex
+	categories map[[2]string]GrowthCurve
+	breeds     map[int]GrowthCurve
+}
+
+// NewMemoryGrowthCurveStore returns an empty thread-safe GrowthCurveStore
+//
+// Unlike the SQL store, it keeps the curves of deleted breeds
+func NewMemoryGrowthCurveStore() GrowthCurveStore {
+	return &memoryGrowthCurveStore{categories: map[[2]string]GrowthCurve{}, breeds: map[int]GrowthCurve{}}
+}
+
+func (s *memoryGrowthCurveStore) List(ctx context.Context) ([]GrowthCurve, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	curves := make([]GrowthCurve, 0, len(s.categories))
+	for _, curve := range s.categories {
+		curves = append(curves, curve)
+	}
+	sort.Slice(curves, func(i, j int) bool {
+		if curves[i].Species != curves[j].Species {
+			return curves[i].Species < curves[j].Species
+		}
+		return curves[i].PetSize < curves[j].PetSize
+	})
+	return curves, nil
+}
+
+func (s *memoryGrowthCurveStore) Get(ctx context.Context, species, petSize string) (GrowthCurve, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	curve, ok := s.categories[[2]string{species, petSize}]
+	if !ok {
+		return GrowthCurve{}, ErrGrowthCurveNotFound
+	}
+	return curve, nil
+}
+
+func (s *memoryGrowthCurveStore) Set(ctx context.Context, curve GrowthCurve) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.categories[[2]string{curve.Species, curve.PetSize}] = curve
+	return nil
+}
+
+func (s *memoryGrowthCurveStore) GetBreed(ctx context.Context, breedID int) (GrowthCurve, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	curve, ok := s.breeds[breedID]
+	if !ok {
+		return GrowthCurve{}, ErrGrowthCurveNotFound
+	}
+	return curve, nil
+}
+
+func (s *memoryGrowthCurveStore) SetBreed(ctx context.Context, curve GrowthCurve) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breeds[curve.BreedID] = curve
+	return nil
+}
+
+func (s *memoryGrowthCurveStore) DeleteBreed(ctx context.Context, breedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.breeds[breedID]; !ok {
+		return ErrGrowthCurveNotFound
+	}
+	delete(s.breeds, breedID)
+	return nil
+}
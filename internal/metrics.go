@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors of the API, served by Handler
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	importedRows    *prometheus.CounterVec
+}
+
+// NewMetrics registers the HTTP, import, Go runtime and process collectors on a dedicated registry
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of the HTTP requests, by method and route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		importedRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "breeds_import_rows_total",
+			Help: "Rows handled by the breeds CSV import, by result (imported or failed).",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.importedRows,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m.importedRows.WithLabelValues("imported")
+	m.importedRows.WithLabelValues("failed")
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db, labelled with name
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterMigrations exports the schema version read by the migrator at every scrape
+func (m *Metrics) RegisterMigrations() {
+	m.registry.MustRegister(migrationCollector{})
+}
+
+// ObserveImport counts the rows of a breeds import
+func (m *Metrics) ObserveImport(stats database_actions.ImportStats) {
+	m.importedRows.WithLabelValues("imported").Add(float64(stats.Imported))
+	m.importedRows.WithLabelValues("failed").Add(float64(stats.Failed))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeRequest is called by LogRequests once a request is served
+func (m *Metrics) observeRequest(method, route string, status int, latency time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(latency.Seconds())
+}
+
+var (
+	schemaVersionDesc = prometheus.NewDesc("schema_migration_version",
+		"Current schema version, 0 when no migration was applied.", nil, nil)
+	schemaDirtyDesc = prometheus.NewDesc("schema_migration_dirty",
+		"1 when the last migration failed halfway.", nil, nil)
+	schemaPendingDesc = prometheus.NewDesc("schema_migrations_pending",
+		"Migrations not applied yet.", nil, nil)
+)
+
+// migrationCollector reads the migration status on scrape, it exports nothing when it cannot be read
+type migrationCollector struct{}
+
+func (migrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- schemaVersionDesc
+	ch <- schemaDirtyDesc
+	ch <- schemaPendingDesc
+}
+
+func (migrationCollector) Collect(ch chan<- prometheus.Metric) {
+	status, err := database_actions.GetMigrationStatus()
+	if err != nil {
+		return
+	}
+	var version, dirty float64
+	if status.Version != nil {
+		version = float64(*status.Version)
+	}
+	if status.Dirty {
+		dirty = 1
+	}
+	ch <- prometheus.MustNewConstMetric(schemaVersionDesc, prometheus.GaugeValue, version)
+	ch <- prometheus.MustNewConstMetric(schemaDirtyDesc, prometheus.GaugeValue, dirty)
+	ch <- prometheus.MustNewConstMetric(schemaPendingDesc, prometheus.GaugeValue, float64(len(status.Pending)))
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func TestMetrics(t *testing.T) {
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.Metrics = NewMetrics()
+	r := mux.NewRouter()
+	r.Use(CaptureRoute)
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	r.Handle("/metrics", app.Metrics.Handler())
+	handler := app.LogRequests(r)
+
+	for _, path := range []string{"/v1/breeds/1", "/v1/breeds/2", "/v1/breeds/42", "/v2/nothing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	app.Metrics.ObserveImport(database_actions.ImportStats{Imported: 3, Failed: 1})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/v1/breeds/{id:[0-9]+}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/v1/breeds/{id:[0-9]+}",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/v1/breeds/{id:[0-9]+}"} 3`,
+		`breeds_import_rows_total{result="imported"} 3`,
+		`breeds_import_rows_total{result="failed"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
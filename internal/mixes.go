@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BreedShare is the part, in percent, of a breed in a mixed breed
+type BreedShare struct {
+	BreedID int `json:"breed_id"`
+	Share   int `json:"share"`
+}
+
+// validateShares checks that shares are of distinct breeds and sum to 100
+func validateShares(shares []BreedShare) error {
+	total := 0
+	seen := map[int]bool{}
+	for _, share := range shares {
+		switch {
+		case share.BreedID < 1:
+			return errors.New("breeds: breed_id must be a positive integer")
+		case share.Share < 1 || share.Share > 100:
+			return errors.New("breeds: share must be a percentage between 1 and 100")
+		case seen[share.BreedID]:
+			return fmt.Errorf("breeds: breed %d is listed twice", share.BreedID)
+		}
+		seen[share.BreedID] = true
+		total += share.Share
+	}
+	if total != 100 {
+		return fmt.Errorf("breeds: the shares sum to %d, not 100", total)
+	}
+	return nil
+}
+
+// sortShares orders shares from the largest, then by breed id, as the stores return them
+func sortShares(shares []BreedShare) {
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share != shares[j].Share {
+			return shares[i].Share > shares[j].Share
+		}
+		return shares[i].BreedID < shares[j].BreedID
+	})
+}
+
+// MixComponent is a breed of a MixEstimate, Weight being its adult weight for the sex of the estimate
+type MixComponent struct {
+	BreedShare
+	Name    string  `json:"name"`
+	PetSize string  `json:"pet_size"`
+	Weight  float64 `json:"weight"`
+}
+
+// MixEstimate is the expected adult weight, in grams, and pet size of a mixed breed
+type MixEstimate struct {
+	Species   string         `json:"species"`
+	Sex       Sex            `json:"sex,omitempty"`
+	Breeds    []MixComponent `json:"breeds"`
+	Weight    float64        `json:"weight"`
+	WeightMin float64        `json:"weight_min"`
+	WeightMax float64        `json:"weight_max"`
+	// PetSize is the size class of Weight, absent when the species has no size classes
+	PetSize string `json:"pet_size,omitempty"`
+}
+
+// estimateMix averages the weights of breeds, weighted by their shares, which need not sum to 100
+//
+// The range spans the lighter sex to the heavier one of each breed, and the size is the class of the expected weight
+func estimateMix(breeds []Breed, shares []BreedShare, sex Sex, classes SizeClasses) MixEstimate {
+	estimate := MixEstimate{Species: breeds[0].Species, Sex: sex, Breeds: []MixComponent{}}
+	total := 0.0
+	for i, breed := range breeds {
+		share := float64(shares[i].Share)
+		total += share
+		estimate.Weight += share * breed.AdultWeight(sex)
+		estimate.WeightMin += share * math.Min(breed.WeightMin, breed.WeightMax)
+		estimate.WeightMax += share * math.Max(breed.WeightMin, breed.WeightMax)
+		estimate.Breeds = append(estimate.Breeds, MixComponent{
+			BreedShare: shares[i],
+			Name:       breed.Name,
+			PetSize:    breed.PetSize,
+			Weight:     breed.AdultWeight(sex),
+		})
+	}
+	estimate.Weight = math.Round(estimate.Weight / total)
+	estimate.WeightMin = math.Round(estimate.WeightMin / total)
+	estimate.WeightMax = math.Round(estimate.WeightMax / total)
+	estimate.PetSize, _ = classes.Classify(estimate.Species, estimate.Weight)
+	return estimate
+}
+
+// parseShares reads a `breeds` query parameter such as 12:75,40:25, the share following each breed id
+func parseShares(value string) ([]BreedShare, error) {
+	shares := []BreedShare{}
+	for _, part := range strings.Split(value, ",") {
+		id, share, found := strings.Cut(part, ":")
+		breedID, err := strconv.Atoi(id)
+		if err != nil || !found {
+			return nil, fmt.Errorf("breeds: %q is not a breed_id:share pair", part)
+		}
+		percent, err := strconv.Atoi(share)
+		if err != nil {
+			return nil, fmt.Errorf("breeds: %q is not a breed_id:share pair", part)
+		}
+		shares = append(shares, BreedShare{BreedID: breedID, Share: percent})
+	}
+	return shares, validateShares(shares)
+}
+
+// EstimateMix estimates the adult weight and size of the mix of the `breeds` query parameter, or of the pet
+// `pet_id`
+//
+// The breeds of a mix must be of one species. A pet is estimated from its breeds, or its main breed when it has
+// none, the deleted ones being left out
+func (a *App) EstimateMix(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	sex := Sex(query.Get("sex"))
+	if sex != "" && sex != SexMale && sex != SexFemale {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("sex must be %s or %s", SexMale, SexFemale))
+		return
+	}
+	petID, mix := query.Get("pet_id"), query.Get("breeds")
+	if (petID == "") == (mix == "") {
+		writeProblem(w, http.StatusBadRequest, "either pet_id or breeds is required")
+		return
+	}
+	if petID != "" && !a.allowed(r, PermissionPetsRead) {
+		writeProblem(w, http.StatusForbidden, fmt.Sprintf("pet_id requires the %s permission", PermissionPetsRead))
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.mix")
+	defer cancel()
+	var shares []BreedShare
+	if petID != "" {
+		id, err := strconv.Atoi(petID)
+		if err != nil || id < 1 {
+			writeProblem(w, http.StatusBadRequest, "pet_id must be a positive integer")
+			return
+		}
+		pet, err := a.Pets.Get(ctx, id)
+		if errors.Is(err, ErrPetNotFound) {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("pet_id: no pet has id %d", id))
+			return
+		}
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch pet", "pet_id", id)
+			return
+		}
+		if sex == "" {
+			sex = pet.Sex
+		}
+		shares = pet.Breeds
+		if len(shares) == 0 && pet.BreedID != nil {
+			shares = []BreedShare{{BreedID: *pet.BreedID, Share: 100}}
+		}
+	} else {
+		var err error
+		shares, err = parseShares(mix)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	breeds := []Breed{}
+	known := []BreedShare{}
+	for _, share := range shares {
+		breed, err := a.Store.Get(ctx, share.BreedID)
+		if errors.Is(err, ErrBreedNotFound) && petID != "" {
+			continue
+		}
+		if errors.Is(err, ErrBreedNotFound) {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("breeds: no breed has id %d", share.BreedID))
+			return
+		}
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch breed", "breed_id", share.BreedID)
+			return
+		}
+		if len(breeds) > 0 && breed.Species != breeds[0].Species {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("breeds: %s is a %s breed, %s a %s one", breed.Name, breed.Species, breeds[0].Name, breeds[0].Species))
+			return
+		}
+		breeds = append(breeds, breed)
+		known = append(known, share)
+	}
+	if len(breeds) == 0 {
+		writeProblem(w, http.StatusBadRequest, fmt.Sprintf("pet_id: the breeds of pet %s are unknown", petID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estimateMix(breeds, known, sex, a.SizeClasses))
+}
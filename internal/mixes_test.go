@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEstimateMixSize(t *testing.T) {
+	chihuahua := Breed{Name: "chihuahua", Species: "dog", PetSize: "small", WeightMin: 2000, WeightMax: 2000}
+	labrador := Breed{Name: "labrador", Species: "dog", PetSize: "tall", WeightMin: 32000, WeightMax: 28000}
+	custom := Breed{Name: "custom", Species: "dog", PetSize: unknownPetSize, WeightMin: 10000, WeightMax: 10000}
+	rabbit := Breed{Name: "rex", Species: "rabbit", PetSize: unknownPetSize, WeightMin: 3500, WeightMax: 3500}
+	tests := []struct {
+		name   string
+		breeds []Breed
+		shares []BreedShare
+		want   MixEstimate
+	}{
+		{name: "half small half tall", breeds: []Breed{chihuahua, labrador}, shares: []BreedShare{{1, 50}, {2, 50}},
+			want: MixEstimate{Weight: 16000, WeightMin: 15000, WeightMax: 17000, PetSize: "medium"}},
+		{name: "mostly tall", breeds: []Breed{labrador, chihuahua}, shares: []BreedShare{{2, 80}, {1, 20}},
+			want: MixEstimate{Weight: 24400, WeightMin: 22800, WeightMax: 26000, PetSize: "medium"}},
+		{name: "mostly a breed of unknown size", breeds: []Breed{custom, chihuahua}, shares: []BreedShare{{3, 90}, {1, 10}},
+			want: MixEstimate{Weight: 9200, WeightMin: 9200, WeightMax: 9200, PetSize: "small"}},
+		{name: "shares not summing to 100", breeds: []Breed{custom}, shares: []BreedShare{{3, 40}},
+			want: MixEstimate{Weight: 10000, WeightMin: 10000, WeightMax: 10000, PetSize: "medium"}},
+		{name: "species without size classes", breeds: []Breed{rabbit}, shares: []BreedShare{{4, 100}},
+			want: MixEstimate{Weight: 3500, WeightMin: 3500, WeightMax: 3500}},
+	}
+
+	for _, tt := range tests {
+		got := estimateMix(tt.breeds, tt.shares, "", DefaultSizeClasses)
+		if got.Weight != tt.want.Weight || got.WeightMin != tt.want.WeightMin || got.WeightMax != tt.want.WeightMax || got.PetSize != tt.want.PetSize {
+			t.Errorf("%s: estimateMix() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateMix(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       MixEstimate
+	}{
+		{name: "mix", query: "breeds=1:50,2:50", wantStatus: http.StatusOK,
+			want: MixEstimate{Species: "dog", Weight: 22750, WeightMin: 20000, WeightMax: 25500, PetSize: "medium"}},
+		{name: "mix of a sex", query: "breeds=2:75,1:25&sex=female", wantStatus: http.StatusOK,
+			want: MixEstimate{Species: "dog", Sex: SexFemale, Weight: 27500, WeightMin: 27500, WeightMax: 35250, PetSize: "large"}},
+		{name: "pet of a single breed", query: "pet_id=1", wantStatus: http.StatusOK,
+			want: MixEstimate{Species: "dog", Sex: SexMale, Weight: 45000, WeightMin: 35000, WeightMax: 45000, PetSize: "giant"}},
+		{name: "shares not summing to 100", query: "breeds=1:50,2:40", wantStatus: http.StatusBadRequest},
+		{name: "breed listed twice", query: "breeds=1:50,1:50", wantStatus: http.StatusBadRequest},
+		{name: "mix of species", query: "breeds=1:50,3:50", wantStatus: http.StatusBadRequest},
+		{name: "unknown breed", query: "breeds=1:50,42:50", wantStatus: http.StatusBadRequest},
+		{name: "unknown pet", query: "pet_id=42", wantStatus: http.StatusBadRequest},
+		{name: "both pet and breeds", query: "pet_id=1&breeds=1:100", wantStatus: http.StatusBadRequest},
+		{name: "neither pet nor breeds", query: "sex=male", wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newPetTestRouter(t, &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/mix?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got MixEstimate
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Species != tt.want.Species || got.Sex != tt.want.Sex || got.Weight != tt.want.Weight ||
+				got.WeightMin != tt.want.WeightMin || got.WeightMax != tt.want.WeightMax || got.PetSize != tt.want.PetSize {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// LifeStage sets the energy needs of a pet along with its activity level
+type LifeStage string
+
+const (
+	// LifeStageEarlyGrowth is a puppy under 4 months, kittens need as much energy at both growth stages
+	LifeStageEarlyGrowth LifeStage = "early_growth"
+	// LifeStageGrowth is a puppy from 4 months to adulthood
+	LifeStageGrowth LifeStage = "growth"
+	LifeStageAdult  LifeStage = "adult"
+	LifeStageSenior LifeStage = "senior"
+)
+
+func (s LifeStage) Valid() bool {
+	return s == LifeStageEarlyGrowth || s == LifeStageGrowth || s == LifeStageAdult || s == LifeStageSenior
+}
+
+// Growing tells whether the pet has not reached its adult weight yet
+func (s LifeStage) Growing() bool {
+	return s == LifeStageEarlyGrowth || s == LifeStageGrowth
+}
+
+// activityFactors are the MER/RER ratios of adults, for intact then neutered pets, as recommended by the WSAVA
+var activityFactors = map[string]map[ActivityLevel][2]float64{
+	"dog": {
+		ActivityLow:      {1.4, 1.2},
+		ActivityModerate: {1.8, 1.6},
+		ActivityHigh:     {2.0, 2.0},
+	},
+	"cat": {
+		ActivityLow:      {1.0, 1.0},
+		ActivityModerate: {1.4, 1.2},
+		ActivityHigh:     {1.6, 1.4},
+	},
+}
+
+// stageFactors are the MER/RER ratios of the life stages other than adult, whatever the activity level
+var stageFactors = map[string]map[LifeStage]float64{
+	"dog": {LifeStageEarlyGrowth: 3.0, LifeStageGrowth: 2.0, LifeStageSenior: 1.4},
+	"cat": {LifeStageEarlyGrowth: 2.5, LifeStageGrowth: 2.5, LifeStageSenior: 1.1},
+}
+
+// RestingEnergyRequirement returns the RER in kcal per day of an animal weighing weight grams, 70 × kg^0.75
+func RestingEnergyRequirement(weight float64) float64 {
+	return 70 * math.Pow(weight/1000, 0.75)
+}
+
+// EnergyProfile is what the maintenance energy requirement depends on, besides the weight
+type EnergyProfile struct {
+	Species       string
+	LifeStage     LifeStage
+	Neutered      bool
+	ActivityLevel ActivityLevel
+}
+
+// MERFactor returns the ratio of the maintenance energy requirement to the RER
+func (p EnergyProfile) MERFactor() (float64, error) {
+	activities, ok := activityFactors[p.Species]
+	if !ok {
+		return 0, fmt.Errorf("no energy factors for species %q", p.Species)
+	}
+	if p.LifeStage != LifeStageAdult {
+		factor, ok := stageFactors[p.Species][p.LifeStage]
+		if !ok {
+			return 0, fmt.Errorf("unknown life stage %q", p.LifeStage)
+		}
+		return factor, nil
+	}
+	factors, ok := activities[p.ActivityLevel]
+	if !ok {
+		return 0, fmt.Errorf("unknown activity level %q", p.ActivityLevel)
+	}
+	if p.Neutered {
+		return factors[1], nil
+	}
+	return factors[0], nil
+}
+
+// EnergyRequirement is the answer of GetNutritionRequirements, energies are in kcal per day
+type EnergyRequirement struct {
+	Species       string        `json:"species"`
+	BreedID       *int          `json:"breed_id,omitempty"`
+	Weight        float64       `json:"weight"`
+	WeightSource  string        `json:"weight_source"`
+	LifeStage     LifeStage     `json:"life_stage"`
+	Neutered      bool          `json:"neutered"`
+	ActivityLevel ActivityLevel `json:"activity_level"`
+	RER           float64       `json:"rer"`
+	MERFactor     float64       `json:"mer_factor"`
+	MER           float64       `json:"mer"`
+	// KcalPerKg is the energy density of a food, and GramsPerDay its daily portion, when given
+	KcalPerKg   *float64 `json:"kcal_per_kg,omitempty"`
+	GramsPerDay *float64 `json:"grams_per_day,omitempty"`
+}
+
+// dailyGrams returns the grams of a food of kcalPerKg covering mer kcal
+func dailyGrams(mer, kcalPerKg float64) float64 {
+	return roundTo(mer/kcalPerKg*1000, 1)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// GetNutritionRequirements computes the RER and MER of a pet described by the query parameters
+//
+// The weight, in grams, defaults to the adult weight of the sex of the breed `breed_id`, which also sets the species,
+// except at growth stages where it is far from the adult one
+func (a *App) GetNutritionRequirements(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	profile, sex, err := energyQuery(query)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	profile.Species = query.Get("species")
+	requirement := EnergyRequirement{WeightSource: "request"}
+
+	if value := query.Get("weight"); value != "" {
+		requirement.Weight, err = strconv.ParseFloat(value, 64)
+		if err != nil || requirement.Weight <= 0 {
+			writeProblem(w, http.StatusBadRequest, "weight must be a positive number of grams")
+			return
+		}
+	}
+	if value := query.Get("kcal_per_kg"); value != "" {
+		kcalPerKg, err := strconv.ParseFloat(value, 64)
+		if err != nil || kcalPerKg <= 0 {
+			writeProblem(w, http.StatusBadRequest, "kcal_per_kg must be a positive number")
+			return
+		}
+		requirement.KcalPerKg = &kcalPerKg
+	}
+
+	if value := query.Get("breed_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			writeProblem(w, http.StatusBadRequest, "breed_id must be a positive integer")
+			return
+		}
+		ctx, cancel := a.queryContext(r, "nutrition.requirements")
+		defer cancel()
+		breed, err := a.Store.Get(ctx, id)
+		if errors.Is(err, ErrBreedNotFound) {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("breed_id: no breed has id %d", id))
+			return
+		}
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch breed", "breed_id", id)
+			return
+		}
+		if profile.Species != "" && profile.Species != breed.Species {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("species: %s is a %s breed, not a %s one", breed.Name, breed.Species, profile.Species))
+			return
+		}
+		profile.Species = breed.Species
+		requirement.BreedID = &id
+		if requirement.Weight == 0 {
+			if profile.LifeStage.Growing() {
+				writeProblem(w, http.StatusBadRequest, "weight is required for growth stages")
+				return
+			}
+			requirement.Weight = breed.AdultWeight(sex)
+			requirement.WeightSource = "breed"
+		}
+	}
+	if profile.Species == "" {
+		writeProblem(w, http.StatusBadRequest, "species or breed_id is required")
+		return
+	}
+	if requirement.Weight == 0 {
+		writeProblem(w, http.StatusBadRequest, "weight or breed_id is required")
+		return
+	}
+
+	requirement.Species = profile.Species
+	requirement.LifeStage = profile.LifeStage
+	requirement.Neutered = profile.Neutered
+	requirement.ActivityLevel = profile.ActivityLevel
+	requirement.MERFactor, err = profile.MERFactor()
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rer := RestingEnergyRequirement(requirement.Weight)
+	requirement.RER = roundTo(rer, 1)
+	requirement.MER = roundTo(rer*requirement.MERFactor, 1)
+	if requirement.KcalPerKg != nil {
+		grams := dailyGrams(rer*requirement.MERFactor, *requirement.KcalPerKg)
+		requirement.GramsPerDay = &grams
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requirement)
+}
+
+// energyQuery reads the life_stage, neutered, activity_level and sex query parameters, the species is left empty
+func energyQuery(query url.Values) (EnergyProfile, Sex, error) {
+	profile := EnergyProfile{
+		LifeStage:     LifeStage(query.Get("life_stage")),
+		ActivityLevel: ActivityLevel(query.Get("activity_level")),
+	}
+	if profile.LifeStage == "" {
+		profile.LifeStage = LifeStageAdult
+	}
+	if profile.ActivityLevel == "" {
+		profile.ActivityLevel = ActivityModerate
+	}
+	var err error
+	profile.Neutered, err = queryBool(query.Get("neutered"))
+	if err != nil {
+		return EnergyProfile{}, "", errors.New("neutered must be true or false")
+	}
+	sex := Sex(query.Get("sex"))
+	if sex != "" && sex != SexMale && sex != SexFemale {
+		return EnergyProfile{}, "", fmt.Errorf("sex must be %s or %s", SexMale, SexFemale)
+	}
+	return profile, sex, nil
+}
+
+// queryBool parses an optional boolean query parameter, false when empty
+func queryBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
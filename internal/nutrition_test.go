@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRestingEnergyRequirement(t *testing.T) {
+	if rer := RestingEnergyRequirement(10000); math.Abs(rer-393.6) > 0.1 {
+		t.Errorf("RER of 10 kg = %f, want 393.6", rer)
+	}
+}
+
+func TestMERFactor(t *testing.T) {
+	tests := []struct {
+		profile EnergyProfile
+		want    float64
+		wantErr bool
+	}{
+		{profile: EnergyProfile{Species: "dog", LifeStage: LifeStageAdult, ActivityLevel: ActivityModerate}, want: 1.8},
+		{profile: EnergyProfile{Species: "dog", LifeStage: LifeStageAdult, ActivityLevel: ActivityModerate, Neutered: true}, want: 1.6},
+		{profile: EnergyProfile{Species: "dog", LifeStage: LifeStageEarlyGrowth, ActivityLevel: ActivityLow, Neutered: true}, want: 3.0},
+		{profile: EnergyProfile{Species: "cat", LifeStage: LifeStageAdult, ActivityLevel: ActivityLow}, want: 1.0},
+		{profile: EnergyProfile{Species: "cat", LifeStage: LifeStageSenior, ActivityLevel: ActivityHigh}, want: 1.1},
+		{profile: EnergyProfile{Species: "rabbit", LifeStage: LifeStageAdult, ActivityLevel: ActivityLow}, wantErr: true},
+		{profile: EnergyProfile{Species: "dog", LifeStage: "retired", ActivityLevel: ActivityLow}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.profile.MERFactor()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%+v: MERFactor() = %v, %v, want %v", tt.profile, got, err, tt.want)
+		}
+	}
+}
+
+func TestNutritionRequirements(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       EnergyRequirement
+	}{
+		{name: "weight", query: "species=dog&weight=10000&neutered=true", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "dog", Weight: 10000, WeightSource: "request", RER: 393.6, MERFactor: 1.6, MER: 629.8}},
+		{name: "male breed weight", query: "breed_id=2&sex=male", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "dog", Weight: 45000, WeightSource: "breed", MERFactor: 1.8}},
+		{name: "female breed weight", query: "breed_id=2&sex=female&life_stage=senior", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "dog", Weight: 35000, WeightSource: "breed", MERFactor: 1.4}},
+		{name: "breed average weight", query: "breed_id=3", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "cat", Weight: 3500, WeightSource: "breed", MERFactor: 1.4}},
+		{name: "weight overrides breed", query: "breed_id=3&weight=5000", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "cat", Weight: 5000, WeightSource: "request", MERFactor: 1.4}},
+		{name: "portion", query: "species=dog&weight=10000&neutered=true&kcal_per_kg=3800", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "dog", Weight: 10000, WeightSource: "request", RER: 393.6, MERFactor: 1.6, MER: 629.8}},
+		{name: "growing pet of a breed", query: "breed_id=2&life_stage=growth&weight=12000", wantStatus: http.StatusOK,
+			want: EnergyRequirement{Species: "dog", Weight: 12000, WeightSource: "request", MERFactor: 2.0}},
+		{name: "growth without weight", query: "breed_id=2&life_stage=growth", wantStatus: http.StatusBadRequest},
+		{name: "early growth without weight", query: "breed_id=3&life_stage=early_growth", wantStatus: http.StatusBadRequest},
+		{name: "species mismatch", query: "species=dog&breed_id=3", wantStatus: http.StatusBadRequest},
+		{name: "unknown breed", query: "breed_id=42", wantStatus: http.StatusBadRequest},
+		{name: "no weight", query: "species=cat", wantStatus: http.StatusBadRequest},
+		{name: "no species", query: "weight=4000", wantStatus: http.StatusBadRequest},
+		{name: "invalid life stage", query: "species=cat&weight=4000&life_stage=retired", wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, NewMemoryBreedStore(fixtureBreeds), &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/nutrition/requirements?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got EnergyRequirement
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Species != tt.want.Species || got.Weight != tt.want.Weight || got.WeightSource != tt.want.WeightSource || got.MERFactor != tt.want.MERFactor {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if tt.want.RER != 0 && (got.RER != tt.want.RER || got.MER != tt.want.MER) {
+				t.Errorf("RER, MER = %v, %v, want %v, %v", got.RER, got.MER, tt.want.RER, tt.want.MER)
+			}
+			if got.KcalPerKg != nil && (got.GramsPerDay == nil || *got.GramsPerDay != 165.7) {
+				t.Errorf("grams_per_day = %v, want 165.7", got.GramsPerDay)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
@@ -0,0 +1,313 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Sex is the sex of a pet, breeds have a weight range for each
+type Sex string
+
+const (
+	SexMale   Sex = "male"
+	SexFemale Sex = "female"
+)
+
+// ActivityLevel is how active a pet is, from its owner's point of view
+type ActivityLevel string
+
+const (
+	ActivityLow      ActivityLevel = "low"
+	ActivityModerate ActivityLevel = "moderate"
+	ActivityHigh     ActivityLevel = "high"
+)
+
+func (l ActivityLevel) Valid() bool {
+	return l == ActivityLow || l == ActivityModerate || l == ActivityHigh
+}
+
+// dateLayout is the JSON format of Date
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, YYYY-MM-DD in JSON
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the day of t, at midnight UTC
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, want YYYY-MM-DD", value)
+	}
+	d.Time = t
+	return nil
+}
+
+// Pet is the profile of a customer's animal
+type Pet struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Species string `json:"species"`
+	// BreedID is the breed of the pet, the main one of a mixed breed, nil for mixed breeds of unknown breeds
+	BreedID    *int `json:"breed_id,omitempty"`
+	MixedBreed bool `json:"mixed_breed"`
+	// Breeds are the shares of the breeds of a mixed breed, when known, from the largest
+	Breeds []BreedShare `json:"breeds,omitempty"`
+	// BirthDate is nil when unknown, as for many rescued pets
+	BirthDate *Date `json:"birth_date,omitempty"`
+	Sex       Sex   `json:"sex"`
+	Neutered  bool  `json:"neutered"`
+	// CurrentWeight is in grams, as the weights of the breeds
+	CurrentWeight int           `json:"current_weight"`
+	ActivityLevel ActivityLevel `json:"activity_level"`
+}
+
+// lifeStageAges are the ages, in months, at which the pets of a species leave early growth, reach adulthood and
+// become seniors
+var lifeStageAges = map[string][3]int{
+	"dog": {4, 12, 84},
+	"cat": {4, 12, 132},
+}
+
+// LifeStage returns the life stage of the pet on day now, adult when its birth date or its species is unknown
+func (p Pet) LifeStage(now time.Time) LifeStage {
+	ages, ok := lifeStageAges[p.Species]
+	if p.BirthDate == nil || !ok {
+		return LifeStageAdult
+	}
+	months := monthsBetween(p.BirthDate.Time, now)
+	switch {
+	case months < ages[0]:
+		return LifeStageEarlyGrowth
+	case months < ages[1]:
+		return LifeStageGrowth
+	case months < ages[2]:
+		return LifeStageAdult
+	}
+	return LifeStageSenior
+}
+
+// monthsBetween returns the number of whole months from since to now
+func monthsBetween(since, now time.Time) int {
+	months := (now.Year()-since.Year())*12 + int(now.Month()-since.Month())
+	if now.Day() < since.Day() {
+		months--
+	}
+	return months
+}
+
+// validate checks the fields of pet which do not depend on the breeds
+func (p Pet) validate(now time.Time) error {
+	switch {
+	case p.Name == "":
+		return errors.New("name is required")
+	case p.Species == "":
+		return errors.New("species is required")
+	case p.BreedID == nil && !p.MixedBreed:
+		return errors.New("breed_id is required unless mixed_breed is set")
+	case p.BreedID != nil && *p.BreedID < 1:
+		return errors.New("breed_id must be a positive integer")
+	case p.Sex != SexMale && p.Sex != SexFemale:
+		return fmt.Errorf("sex must be %s or %s", SexMale, SexFemale)
+	case p.CurrentWeight <= 0:
+		return errors.New("current_weight must be a positive number of grams")
+	case !p.ActivityLevel.Valid():
+		return fmt.Errorf("activity_level must be %s, %s or %s", ActivityLow, ActivityModerate, ActivityHigh)
+	case p.BirthDate != nil && p.BirthDate.After(now):
+		return errors.New("birth_date is in the future")
+	case len(p.Breeds) > 0 && !p.MixedBreed:
+		return errors.New("breeds requires mixed_breed")
+	case len(p.Breeds) == 1:
+		return errors.New("breeds must hold at least two breeds")
+	}
+	if len(p.Breeds) == 0 {
+		return nil
+	}
+	if err := validateShares(p.Breeds); err != nil {
+		return err
+	}
+	if p.BreedID != nil && p.share(*p.BreedID) == 0 {
+		return errors.New("breed_id must be one of breeds")
+	}
+	return nil
+}
+
+// share returns the share of the breed breedID in the pet, 0 when it is not one of its breeds
+func (p Pet) share(breedID int) int {
+	for _, share := range p.Breeds {
+		if share.BreedID == breedID {
+			return share.Share
+		}
+	}
+	return 0
+}
+
+func (a *App) ListPets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "pets.list")
+	defer cancel()
+	pets, err := a.Pets.List(ctx)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch pets")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pets)
+}
+
+func (a *App) GetPet(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "pets.get")
+	defer cancel()
+	pet, err := a.Pets.Get(ctx, id)
+	if errors.Is(err, ErrPetNotFound) {
+		writeProblem(w, http.StatusNotFound, "Pet not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch pet", "pet_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pet)
+}
+
+func (a *App) CreatePet(w http.ResponseWriter, r *http.Request) {
+	pet, ok := a.decodePet(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "pets.create")
+	defer cancel()
+	if !a.checkPetBreed(w, r, ctx, pet) {
+		return
+	}
+	created, err := a.Pets.Create(ctx, pet)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to create pet")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdatePet replaces the pet and returns it
+func (a *App) UpdatePet(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	pet, ok := a.decodePet(w, r)
+	if !ok {
+		return
+	}
+	pet.ID = id
+
+	ctx, cancel := a.queryContext(r, "pets.update")
+	defer cancel()
+	if !a.checkPetBreed(w, r, ctx, pet) {
+		return
+	}
+	err := a.Pets.Update(ctx, pet)
+	if errors.Is(err, ErrPetNotFound) {
+		writeProblem(w, http.StatusNotFound, "Pet not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to update pet", "pet_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pet)
+}
+
+func (a *App) DeletePet(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "pets.delete")
+	defer cancel()
+	err := a.Pets.Delete(ctx, id)
+	if errors.Is(err, ErrPetNotFound) {
+		writeProblem(w, http.StatusNotFound, "Pet not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to delete pet", "pet_id", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodePet reads and validates the pet of the request body, answering 400 when it is invalid
+func (a *App) decodePet(w http.ResponseWriter, r *http.Request) (Pet, bool) {
+	var pet Pet
+	if err := json.NewDecoder(r.Body).Decode(&pet); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return Pet{}, false
+	}
+	if err := pet.validate(time.Now()); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return Pet{}, false
+	}
+	if len(pet.Breeds) > 0 {
+		sortShares(pet.Breeds)
+		if pet.BreedID == nil {
+			pet.BreedID = &pet.Breeds[0].BreedID
+		}
+	}
+	return pet, true
+}
+
+// checkPetBreed answers 400 when a breed of pet does not exist or is of another species
+//
+// The main breed of a mixed breed is one of its breeds, it is only checked once
+func (a *App) checkPetBreed(w http.ResponseWriter, r *http.Request, ctx context.Context, pet Pet) bool {
+	field, ids := "breeds", []int{}
+	for _, share := range pet.Breeds {
+		ids = append(ids, share.BreedID)
+	}
+	if len(ids) == 0 && pet.BreedID != nil {
+		field, ids = "breed_id", []int{*pet.BreedID}
+	}
+	for _, id := range ids {
+		breed, err := a.Store.Get(ctx, id)
+		if errors.Is(err, ErrBreedNotFound) {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("%s: no breed has id %d", field, id))
+			return false
+		}
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch the breed of the pet", "breed_id", id)
+			return false
+		}
+		if breed.Species != pet.Species {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("species: %s is a %s breed, not a %s one", breed.Name, breed.Species, pet.Species))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+// newPetTestRouter serves fixtureBreeds and a pet of id 1, a mixed breed akita, checked against the spec
+func newPetTestRouter(t *testing.T, logs *bytes.Buffer) *mux.Router {
+	t.Helper()
+	validator, err := NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	app := NewApp(charmLog.New(logs))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.Pets = NewMemoryPetStore(app.Store)
+	app.Validator = validator
+
+	akita := 2
+	_, err = app.Pets.Create(context.Background(), Pet{Name: "Hachi", Species: "dog", BreedID: &akita, MixedBreed: true, Sex: SexMale, CurrentWeight: 40000, ActivityLevel: ActivityModerate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	return r
+}
+
+func TestPetHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "list", method: http.MethodGet, path: "/v1/pets", wantStatus: http.StatusOK, wantBody: `"name":"Hachi"`},
+		{name: "get", method: http.MethodGet, path: "/v1/pets/1", wantStatus: http.StatusOK, wantBody: `"breed_id":2`},
+		{name: "get unknown", method: http.MethodGet, path: "/v1/pets/42", wantStatus: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Mimi","species":"cat","breed_id":3,"birth_date":"2022-05-01","sex":"female","neutered":true,"current_weight":3500,"activity_level":"low"}`, wantStatus: http.StatusCreated, wantBody: `"birth_date":"2022-05-01"`},
+		{name: "create mixed breed of unknown breeds", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","mixed_breed":true,"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusCreated, wantBody: `"mixed_breed":true`},
+		{name: "create mix", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","mixed_breed":true,"breeds":[{"breed_id":1,"share":25},{"breed_id":2,"share":75}],"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusCreated, wantBody: `"breed_id":2,"mixed_breed":true,"breeds":[{"breed_id":2,"share":75},{"breed_id":1,"share":25}]`},
+		{name: "create mix of shares not summing to 100", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","mixed_breed":true,"breeds":[{"breed_id":1,"share":25},{"breed_id":2,"share":50}],"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusBadRequest, wantBody: "sum to 75"},
+		{name: "create mix of another species", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","mixed_breed":true,"breeds":[{"breed_id":1,"share":50},{"breed_id":3,"share":50}],"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusBadRequest, wantBody: "abyssinian is a cat breed"},
+		{name: "create mix whose main breed is not one of its breeds", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","breed_id":3,"mixed_breed":true,"breeds":[{"breed_id":1,"share":50},{"breed_id":2,"share":50}],"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusBadRequest, wantBody: "breed_id must be one of breeds"},
+		{name: "create breeds of a purebred", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","breeds":[{"breed_id":1,"share":50},{"breed_id":2,"share":50}],"sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusBadRequest, wantBody: "mixed_breed"},
+		{name: "create without breed", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Rox","species":"dog","sex":"female","current_weight":12000,"activity_level":"high"}`, wantStatus: http.StatusBadRequest, wantBody: "mixed_breed"},
+		{name: "create species mismatch", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Mimi","species":"dog","breed_id":3,"sex":"female","current_weight":3500,"activity_level":"low"}`, wantStatus: http.StatusBadRequest, wantBody: "abyssinian is a cat breed"},
+		{name: "create unknown breed", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Mimi","species":"cat","breed_id":42,"sex":"female","current_weight":3500,"activity_level":"low"}`, wantStatus: http.StatusBadRequest, wantBody: "no breed has id 42"},
+		{name: "create invalid sex", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Mimi","species":"cat","breed_id":3,"sex":"unknown","current_weight":3500,"activity_level":"low"}`, wantStatus: http.StatusBadRequest},
+		{name: "create born tomorrow", method: http.MethodPost, path: "/v1/pets", body: `{"name":"Mimi","species":"cat","breed_id":3,"birth_date":"2999-01-01","sex":"female","current_weight":3500,"activity_level":"low"}`, wantStatus: http.StatusBadRequest, wantBody: "future"},
+		{name: "update", method: http.MethodPut, path: "/v1/pets/1", body: `{"name":"Hachi","species":"dog","breed_id":2,"sex":"male","neutered":true,"current_weight":41000,"activity_level":"low"}`, wantStatus: http.StatusOK, wantBody: `"current_weight":41000`},
+		{name: "update unknown", method: http.MethodPut, path: "/v1/pets/42", body: `{"name":"Hachi","species":"dog","breed_id":2,"sex":"male","current_weight":41000,"activity_level":"low"}`, wantStatus: http.StatusNotFound},
+		{name: "update species mismatch", method: http.MethodPut, path: "/v1/pets/1", body: `{"name":"Hachi","species":"cat","breed_id":2,"sex":"male","current_weight":41000,"activity_level":"low"}`, wantStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/v1/pets/1", wantStatus: http.StatusNoContent},
+		{name: "delete unknown", method: http.MethodDelete, path: "/v1/pets/42", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			r := newPetTestRouter(t, &logs)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %s lacks %s", rec.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(logs.String(), "Response does not match") {
+				t.Errorf("response flagged as not matching the spec: %s", logs.String())
+			}
+		})
+	}
+}
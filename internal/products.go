@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ProductKind is the texture of a food
+type ProductKind string
+
+const (
+	ProductKibble ProductKind = "kibble"
+	ProductWet    ProductKind = "wet"
+)
+
+// Product is a food of the catalog
+type Product struct {
+	ID        int         `json:"id"`
+	Name      string      `json:"name"`
+	Kind      ProductKind `json:"kind"`
+	KcalPerKg int         `json:"kcal_per_kg"`
+	Species   string      `json:"species"`
+	// PetSizes are the pet sizes of the breeds the product is made for, empty for all of them
+	PetSizes []string `json:"pet_sizes"`
+	// LifeStages are the life stages the product is made for, empty for all of them
+	LifeStages []LifeStage `json:"life_stages"`
+}
+
+func (p Product) validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New("name is required")
+	case p.Kind != ProductKibble && p.Kind != ProductWet:
+		return fmt.Errorf("kind must be %s or %s", ProductKibble, ProductWet)
+	case p.KcalPerKg <= 0:
+		return errors.New("kcal_per_kg must be positive")
+	case p.Species == "":
+		return errors.New("species is required")
+	}
+	for _, size := range p.PetSizes {
+		if size == "" {
+			return errors.New("pet_sizes cannot hold empty sizes")
+		}
+	}
+	for _, stage := range p.LifeStages {
+		if !stage.Valid() {
+			return fmt.Errorf("unknown life stage %q", stage)
+		}
+	}
+	return nil
+}
+
+// suits reports whether the product is made for a pet of species, petSize and stage, an empty petSize, that of
+// mixed breeds of unknown breeds, matching every size
+func (p Product) suits(species, petSize string, stage LifeStage) bool {
+	if p.Species != species {
+		return false
+	}
+	if petSize != "" && len(p.PetSizes) > 0 && !contains(p.PetSizes, petSize) {
+		return false
+	}
+	return len(p.LifeStages) == 0 || contains(p.LifeStages, stage)
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withEmptyLists replaces nil lists, for the JSON answers to hold arrays
+func (p Product) withEmptyLists() Product {
+	if p.PetSizes == nil {
+		p.PetSizes = []string{}
+	}
+	if p.LifeStages == nil {
+		p.LifeStages = []LifeStage{}
+	}
+	return p
+}
+
+func (a *App) ListProducts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "products.list")
+	defer cancel()
+	products, err := a.Products.List(ctx, ProductFilter{Species: r.URL.Query().Get("species")})
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch products")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+func (a *App) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "products.get")
+	defer cancel()
+	product, err := a.Products.Get(ctx, id)
+	if errors.Is(err, ErrProductNotFound) {
+		writeProblem(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch product", "product_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (a *App) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := a.decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "products.create")
+	defer cancel()
+	created, err := a.Products.Create(ctx, product)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to create product")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdateProduct replaces the product and returns it
+func (a *App) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	product, ok := a.decodeProduct(w, r)
+	if !ok {
+		return
+	}
+	product.ID = id
+
+	ctx, cancel := a.queryContext(r, "products.update")
+	defer cancel()
+	err := a.Products.Update(ctx, product)
+	if errors.Is(err, ErrProductNotFound) {
+		writeProblem(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to update product", "product_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (a *App) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	ctx, cancel := a.queryContext(r, "products.delete")
+	defer cancel()
+	err := a.Products.Delete(ctx, id)
+	if errors.Is(err, ErrProductNotFound) {
+		writeProblem(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to delete product", "product_id", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeProduct reads and validates the product of the request body, answering 400 when it is invalid
+func (a *App) decodeProduct(w http.ResponseWriter, r *http.Request) (Product, bool) {
+	var product Product
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		a.log(r).Warn("Invalid request body", "err", err)
+		writeProblem(w, http.StatusBadRequest, "Invalid request body")
+		return Product{}, false
+	}
+	if err := product.validate(); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return Product{}, false
+	}
+	return product.withEmptyLists(), true
+}
+
+// Recommendation is the answer of RecommendProducts, MER is in kcal per day
+type Recommendation struct {
+	Species   string    `json:"species"`
+	PetSize   string    `json:"pet_size,omitempty"`
+	Weight    float64   `json:"weight"`
+	LifeStage LifeStage `json:"life_stage"`
+	MER       float64   `json:"mer"`
+	Products  []Portion `json:"products"`
+}
+
+// Portion is the daily ration of a product covering the MER of a Recommendation
+type Portion struct {
+	Product
+	GramsPerDay float64 `json:"grams_per_day"`
+}
+
+// RecommendProducts returns the daily portion of each product made for the pet `pet_id`, or for a pet of the breed
+// `breed_id` described by the query parameters of GetNutritionRequirements, `weight` being required at growth stages
+//
+// A pet is described by its own profile: its current weight, its age and the size of its breed, every size when
+// it has none
+func (a *App) RecommendProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	profile, sex, err := energyQuery(query)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	petID, breedID := query.Get("pet_id"), query.Get("breed_id")
+	if (petID == "") == (breedID == "") {
+		writeProblem(w, http.StatusBadRequest, "either pet_id or breed_id is required")
+		return
+	}
+	if petID != "" && !a.allowed(r, PermissionPetsRead) {
+		writeProblem(w, http.StatusForbidden, fmt.Sprintf("pet_id requires the %s permission", PermissionPetsRead))
+		return
+	}
+
+	ctx, cancel := a.queryContext(r, "products.recommend")
+	defer cancel()
+	var recommendation Recommendation
+	var breedNumber int
+	if petID != "" {
+		id, err := strconv.Atoi(petID)
+		if err != nil || id < 1 {
+			writeProblem(w, http.StatusBadRequest, "pet_id must be a positive integer")
+			return
+		}
+		pet, err := a.Pets.Get(ctx, id)
+		if errors.Is(err, ErrPetNotFound) {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("pet_id: no pet has id %d", id))
+			return
+		}
+		if err != nil {
+			a.storeFailed(w, r, ctx, err, "Failed to fetch pet", "pet_id", id)
+			return
+		}
+		profile = EnergyProfile{Species: pet.Species, LifeStage: pet.LifeStage(time.Now()), Neutered: pet.Neutered, ActivityLevel: pet.ActivityLevel}
+		recommendation.Weight = float64(pet.CurrentWeight)
+		if pet.BreedID != nil {
+			breedNumber = *pet.BreedID
+		}
+	} else {
+		breedNumber, err = strconv.Atoi(breedID)
+		if err != nil || breedNumber < 1 {
+			writeProblem(w, http.StatusBadRequest, "breed_id must be a positive integer")
+			return
+		}
+		if value := query.Get("weight"); value != "" {
+			recommendation.Weight, err = strconv.ParseFloat(value, 64)
+			if err != nil || recommendation.Weight <= 0 {
+				writeProblem(w, http.StatusBadRequest, "weight must be a positive number of grams")
+				return
+			}
+		} else if profile.LifeStage.Growing() {
+			writeProblem(w, http.StatusBadRequest, "weight is required for growth stages")
+			return
+		}
+	}
+
+	if breedNumber != 0 {
+		breed, err := a.Store.Get(ctx, breedNumber)
+		switch {
+		case err == nil:
+			if breed.PetSize != unknownPetSize {
+				recommendation.PetSize = breed.PetSize
+			}
+			if petID == "" {
+				profile.Species = breed.Species
+				if recommendation.Weight == 0 {
+					recommendation.Weight = breed.AdultWeight(sex)
+				}
+			}
+		case !errors.Is(err, ErrBreedNotFound):
+			a.storeFailed(w, r, ctx, err, "Failed to fetch breed", "breed_id", breedNumber)
+			return
+		case petID == "":
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("breed_id: no breed has id %d", breedNumber))
+			return
+		}
+	}
+
+	factor, err := profile.MERFactor()
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mer := RestingEnergyRequirement(recommendation.Weight) * factor
+	recommendation.Species = profile.Species
+	recommendation.LifeStage = profile.LifeStage
+	recommendation.MER = roundTo(mer, 1)
+
+	products, err := a.Products.List(ctx, ProductFilter{Species: profile.Species})
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch products")
+		return
+	}
+	recommendation.Products = []Portion{}
+	for _, product := range products {
+		if product.suits(profile.Species, recommendation.PetSize, profile.LifeStage) {
+			recommendation.Products = append(recommendation.Products, Portion{
+				Product:     product,
+				GramsPerDay: dailyGrams(mer, float64(product.KcalPerKg)),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recommendation)
+}
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+// newProductTestRouter serves fixtureBreeds, the pet of newPetTestRouter, a senior cat of id 2 without breed,
+// and products for dogs of every size, large adult dogs, small dogs and cats
+func newProductTestRouter(t *testing.T, logs *bytes.Buffer) *mux.Router {
+	t.Helper()
+	validator, err := NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	app := NewApp(charmLog.New(logs))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.Pets = NewMemoryPetStore(app.Store)
+	app.Products = NewMemoryProductStore()
+	app.Validator = validator
+
+	ctx := context.Background()
+	akita := 2
+	birthDate := NewDate(time.Now().AddDate(-12, 0, 0))
+	pets := []Pet{
+		{Name: "Hachi", Species: "dog", BreedID: &akita, MixedBreed: true, Sex: SexMale, CurrentWeight: 40000, ActivityLevel: ActivityModerate},
+		{Name: "Mimi", Species: "cat", MixedBreed: true, BirthDate: &birthDate, Sex: SexFemale, Neutered: true, CurrentWeight: 4000, ActivityLevel: ActivityLow},
+	}
+	for _, pet := range pets {
+		if _, err := app.Pets.Create(ctx, pet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	products := []Product{
+		{Name: "Dog kibble", Kind: ProductKibble, KcalPerKg: 3800, Species: "dog"},
+		{Name: "Large adult kibble", Kind: ProductKibble, KcalPerKg: 3500, Species: "dog", PetSizes: []string{"large"}, LifeStages: []LifeStage{LifeStageAdult}},
+		{Name: "Small dog pâté", Kind: ProductWet, KcalPerKg: 1100, Species: "dog", PetSizes: []string{"small"}},
+		{Name: "Senior cat pâté", Kind: ProductWet, KcalPerKg: 1000, Species: "cat", LifeStages: []LifeStage{LifeStageSenior}},
+	}
+	for _, product := range products {
+		if _, err := app.Products.Create(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	return r
+}
+
+func TestProductHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "list", method: http.MethodGet, path: "/v1/products", wantStatus: http.StatusOK, wantBody: `"name":"Small dog pâté"`},
+		{name: "list by species", method: http.MethodGet, path: "/v1/products?species=cat", wantStatus: http.StatusOK, wantBody: `[{"id":4,`},
+		{name: "get", method: http.MethodGet, path: "/v1/products/2", wantStatus: http.StatusOK, wantBody: `"pet_sizes":["large"]`},
+		{name: "get unknown", method: http.MethodGet, path: "/v1/products/42", wantStatus: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, path: "/v1/products", body: `{"name":"Kitten kibble","kind":"kibble","kcal_per_kg":4000,"species":"cat","life_stages":["early_growth","growth"]}`, wantStatus: http.StatusCreated, wantBody: `"pet_sizes":[]`},
+		{name: "create invalid kind", method: http.MethodPost, path: "/v1/products", body: `{"name":"Treats","kind":"treat","kcal_per_kg":3000,"species":"dog"}`, wantStatus: http.StatusBadRequest},
+		{name: "create without energy", method: http.MethodPost, path: "/v1/products", body: `{"name":"Water","kind":"wet","kcal_per_kg":0,"species":"dog"}`, wantStatus: http.StatusBadRequest},
+		{name: "create unknown life stage", method: http.MethodPost, path: "/v1/products", body: `{"name":"Kibble","kind":"kibble","kcal_per_kg":3000,"species":"dog","life_stages":["retired"]}`, wantStatus: http.StatusBadRequest},
+		{name: "update", method: http.MethodPut, path: "/v1/products/1", body: `{"name":"Dog kibble","kind":"kibble","kcal_per_kg":3900,"species":"dog"}`, wantStatus: http.StatusOK, wantBody: `"kcal_per_kg":3900`},
+		{name: "update unknown", method: http.MethodPut, path: "/v1/products/42", body: `{"name":"Dog kibble","kind":"kibble","kcal_per_kg":3900,"species":"dog"}`, wantStatus: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, path: "/v1/products/1", wantStatus: http.StatusNoContent},
+		{name: "delete unknown", method: http.MethodDelete, path: "/v1/products/42", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			r := newProductTestRouter(t, &logs)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %s lacks %s", rec.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(logs.String(), "Response does not match") {
+				t.Errorf("response flagged as not matching the spec: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestRecommendProducts(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantStatus   int
+		want         Recommendation
+		wantProducts []string
+	}{
+		{name: "male breed", query: "breed_id=2&sex=male", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "dog", PetSize: "large", Weight: 45000, LifeStage: LifeStageAdult},
+			wantProducts: []string{"Dog kibble", "Large adult kibble"}},
+		{name: "puppy of a breed", query: "breed_id=2&life_stage=growth&weight=12000", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "dog", PetSize: "large", Weight: 12000, LifeStage: LifeStageGrowth},
+			wantProducts: []string{"Dog kibble"}},
+		{name: "puppy of a breed without weight", query: "breed_id=2&life_stage=early_growth", wantStatus: http.StatusBadRequest},
+		{name: "zero weight", query: "breed_id=2&weight=0", wantStatus: http.StatusBadRequest},
+		{name: "small breed", query: "breed_id=1", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "dog", PetSize: "small", Weight: 5500, LifeStage: LifeStageAdult},
+			wantProducts: []string{"Dog kibble", "Small dog pâté"}},
+		{name: "pet", query: "pet_id=1&life_stage=senior", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "dog", PetSize: "large", Weight: 40000, LifeStage: LifeStageAdult},
+			wantProducts: []string{"Dog kibble", "Large adult kibble"}},
+		{name: "senior pet without breed", query: "pet_id=2", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "cat", Weight: 4000, LifeStage: LifeStageSenior},
+			wantProducts: []string{"Senior cat pâté"}},
+		{name: "no products", query: "breed_id=3", wantStatus: http.StatusOK,
+			want:         Recommendation{Species: "cat", PetSize: "medium", Weight: 3500, LifeStage: LifeStageAdult},
+			wantProducts: []string{}},
+		{name: "neither pet nor breed", query: "sex=male", wantStatus: http.StatusBadRequest},
+		{name: "both pet and breed", query: "pet_id=1&breed_id=2", wantStatus: http.StatusBadRequest},
+		{name: "unknown breed", query: "breed_id=42", wantStatus: http.StatusBadRequest},
+		{name: "unknown pet", query: "pet_id=42", wantStatus: http.StatusBadRequest},
+		{name: "invalid life stage", query: "breed_id=2&life_stage=retired", wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newProductTestRouter(t, &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/products/recommendations?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got Recommendation
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Species != tt.want.Species || got.PetSize != tt.want.PetSize || got.Weight != tt.want.Weight || got.LifeStage != tt.want.LifeStage {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			names := []string{}
+			for _, portion := range got.Products {
+				names = append(names, portion.Name)
+				if want := dailyGrams(got.MER, float64(portion.KcalPerKg)); portion.GramsPerDay < want-0.2 || portion.GramsPerDay > want+0.2 {
+					t.Errorf("%s: grams_per_day = %v, want about %v", portion.Name, portion.GramsPerDay, want)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantProducts, ",") {
+				t.Errorf("products = %v, want %v", names, tt.wantProducts)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
+
+func TestPetLifeStage(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		species   string
+		birthDate time.Time
+		want      LifeStage
+	}{
+		{species: "dog", birthDate: now.AddDate(0, -3, 0), want: LifeStageEarlyGrowth},
+		{species: "dog", birthDate: now.AddDate(0, -4, 0), want: LifeStageGrowth},
+		{species: "dog", birthDate: now.AddDate(-1, 0, 1), want: LifeStageGrowth},
+		{species: "dog", birthDate: now.AddDate(-1, 0, 0), want: LifeStageAdult},
+		{species: "dog", birthDate: now.AddDate(-8, 0, 0), want: LifeStageSenior},
+		{species: "cat", birthDate: now.AddDate(-8, 0, 0), want: LifeStageAdult},
+		{species: "rabbit", birthDate: now.AddDate(0, -1, 0), want: LifeStageAdult},
+	}
+
+	for _, tt := range tests {
+		birthDate := NewDate(tt.birthDate)
+		pet := Pet{Species: tt.species, BirthDate: &birthDate}
+		if got := pet.LifeStage(now); got != tt.want {
+			t.Errorf("%s born %s: LifeStage() = %s, want %s", tt.species, birthDate.Format(dateLayout), got, tt.want)
+		}
+	}
+	if got := (Pet{Species: "dog"}).LifeStage(now); got != LifeStageAdult {
+		t.Errorf("LifeStage() without birth date = %s, want adult", got)
+	}
+}
@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket holding up to Burst tokens, refilled with Requests tokens every Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParseRateLimit reads "<requests>/<s|m|h>", e.g. "60/m", the burst being the number of requests
+func ParseRateLimit(value string) (RateLimit, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(value), "/")
+	requests, err := strconv.Atoi(count)
+	if !found || err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected e.g. 60/m", value)
+	}
+	period, ok := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit period %q, expected s, m or h", unit)
+	}
+	return RateLimit{Requests: requests, Period: period, Burst: requests}, nil
+}
+
+func (l RateLimit) refillInterval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// LimitResult is the state of a bucket after a Take
+type LimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request was not allowed
+	RetryAfter time.Duration
+}
+
+// LimiterStore keeps the token buckets, the in-process NewMemoryLimiterStore or a store shared between instances
+type LimiterStore interface {
+	// Take consumes one token of the bucket key if there is one
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (LimitResult, error)
+	// Peek reports the bucket key as Take would, without consuming a token
+	Peek(ctx context.Context, key string, limit RateLimit, now time.Time) (LimitResult, error)
+}
+
+// RateLimiter limits each caller separately on read (GET, HEAD, OPTIONS) and on write routes
+//
+// Failed authentications are limited per IP with AuthFailures, before credentials are looked up, no limit applying
+// when it is zero
+type RateLimiter struct {
+	Store        LimiterStore
+	Read         RateLimit
+	Write        RateLimit
+	AuthFailures RateLimit
+}
+
+// rateLimit answers 429 once the caller bucket is empty, the limiter store failing open
+func (a *App) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, limit := "write", a.RateLimiter.Write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			class, limit = "read", a.RateLimiter.Read
+		}
+
+		result, err := a.RateLimiter.Store.Take(r.Context(), class+":"+rateLimitKey(r), limit, time.Now())
+		if err != nil {
+			a.log(r).Error("Rate limiter unavailable", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			writeProblem(w, http.StatusTooManyRequests, fmt.Sprintf("Rate limit of %d %s requests exceeded", limit.Burst, class))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey identifies the caller by its authenticated subject, or by its IP for anonymous requests
+func rateLimitKey(r *http.Request) string {
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		return identity.Subject
+	}
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// authFailuresExceeded answers 429 when the IP of the request has failed authenticating too often, before its
+// credentials are looked up; the limiter store fails open
+func (a *App) authFailuresExceeded(w http.ResponseWriter, r *http.Request) bool {
+	if a.RateLimiter == nil || a.RateLimiter.AuthFailures.Requests == 0 {
+		return false
+	}
+	limit := a.RateLimiter.AuthFailures
+	result, err := a.RateLimiter.Store.Peek(r.Context(), "auth_failures:"+ipKey(r), limit, time.Now())
+	if err != nil {
+		a.log(r).Error("Rate limiter unavailable", "err", err)
+		return false
+	}
+	if result.Allowed {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	writeProblem(w, http.StatusTooManyRequests, fmt.Sprintf("Limit of %d failed authentications exceeded", limit.Burst))
+	return true
+}
+
+// recordAuthFailure consumes a token of the failed authentications bucket of the IP of the request
+func (a *App) recordAuthFailure(r *http.Request) {
+	if a.RateLimiter == nil || a.RateLimiter.AuthFailures.Requests == 0 {
+		return
+	}
+	_, err := a.RateLimiter.Store.Take(r.Context(), "auth_failures:"+ipKey(r), a.RateLimiter.AuthFailures, time.Now())
+	if err != nil {
+		a.log(r).Error("Rate limiter unavailable", "err", err)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled entirely
+	full time.Time
+}
+
+type memoryLimiterStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryLimiterStore keeps the buckets in process, each instance of the API then limits on its own
+func NewMemoryLimiterStore() LimiterStore {
+	return &memoryLimiterStore{buckets: map[string]*bucket{}}
+}
+
+func (s *memoryLimiterStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (LimitResult, error) {
+	return s.use(key, limit, now, true), nil
+}
+
+func (s *memoryLimiterStore) Peek(ctx context.Context, key string, limit RateLimit, now time.Time) (LimitResult, error) {
+	return s.use(key, limit, now, false), nil
+}
+
+// use refills the bucket key, and consumes one of its tokens when take is set and there is one
+func (s *memoryLimiterStore) use(key string, limit RateLimit, now time.Time, take bool) LimitResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		if !take {
+			return LimitResult{Allowed: true, Remaining: limit.Burst}
+		}
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	interval := limit.refillInterval()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+float64(now.Sub(b.updated))/float64(interval))
+	b.updated = now
+
+	result := LimitResult{Allowed: b.tokens >= 1}
+	if result.Allowed && take {
+		b.tokens--
+	} else if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit.Burst) - b.tokens) * float64(interval))
+	b.full = now.Add(result.Reset)
+	return result
+}
+
+// sweep drops, at most once a minute, the buckets which have refilled entirely as they would be recreated full
+func (s *memoryLimiterStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{value: "60/m", want: RateLimit{Requests: 60, Period: time.Minute, Burst: 60}},
+		{value: " 5/s", want: RateLimit{Requests: 5, Period: time.Second, Burst: 5}},
+		{value: "60", wantErr: true},
+		{value: "0/m", wantErr: true},
+		{value: "60/d", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestMemoryLimiterStore(t *testing.T) {
+	store := NewMemoryLimiterStore()
+	limit := RateLimit{Requests: 2, Period: time.Second, Burst: 2}
+	now := time.Now()
+	take := func(key string, at time.Time) LimitResult {
+		result, err := store.Take(context.Background(), key, limit, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result, _ := store.Peek(context.Background(), "a", limit, now); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("peek of a new bucket = %+v", result)
+	}
+	if result := take("a", now); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("first take = %+v", result)
+	}
+	if result, _ := store.Peek(context.Background(), "a", limit, now); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("peek = %+v, should not consume a token", result)
+	}
+	if result := take("a", now); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("second take = %+v", result)
+	}
+	result := take("a", now)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond || result.Reset != time.Second {
+		t.Fatalf("third take = %+v, want a denial with a 500ms retry", result)
+	}
+	if result := take("b", now); !result.Allowed {
+		t.Errorf("another key should have its own bucket")
+	}
+	if result := take("a", now.Add(500*time.Millisecond)); !result.Allowed {
+		t.Errorf("a token should have been refilled after 500ms")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	app.RateLimiter = &RateLimiter{
+		Store: NewMemoryLimiterStore(),
+		Read:  RateLimit{Requests: 2, Period: time.Minute, Burst: 2},
+		Write: RateLimit{Requests: 1, Period: time.Minute, Burst: 1},
+	}
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	do := func(method, path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do(http.MethodGet, "/v1/breeds", "10.0.0.1:1234"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatalf("read %d: status %d, RateLimit-Limit %q", i, rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+	rec := do(http.MethodGet, "/v1/breeds/search", "10.0.0.1:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("third read: status %d, headers %v", rec.Code, rec.Header())
+	}
+	if rec := do(http.MethodGet, "/v1/breeds", "10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("another IP: status %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/breeds/1", "10.0.0.1:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("writes have their own limit: status %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/breeds/2", "10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second write: status %d", rec.Code)
+	}
+}
+
+// countingKeyStore counts the API key lookups
+type countingKeyStore struct {
+	APIKeyStore
+	lookups int
+}
+
+func (s *countingKeyStore) FindByHash(ctx context.Context, hash string) (APIKey, error) {
+	s.lookups++
+	return s.APIKeyStore.FindByHash(ctx, hash)
+}
+
+func TestAuthFailuresRateLimit(t *testing.T) {
+	app := NewApp(charmLog.New(io.Discard))
+	app.Store = NewMemoryBreedStore(fixtureBreeds)
+	keys := &countingKeyStore{APIKeyStore: NewMemoryAPIKeyStore()}
+	app.Auth = &Authenticator{Keys: keys, Roles: NewMemoryRoleStore()}
+	app.RateLimiter = &RateLimiter{
+		Store:        NewMemoryLimiterStore(),
+		Read:         RateLimit{Requests: 100, Period: time.Minute, Burst: 100},
+		Write:        RateLimit{Requests: 100, Period: time.Minute, Burst: 100},
+		AuthFailures: RateLimit{Requests: 3, Period: time.Minute, Burst: 3},
+	}
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/breeds", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Api-Key", apiKeyPrefix+"guessed")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	status := 0
+	attempts := 0
+	for ; attempts < 10 && status != http.StatusTooManyRequests; attempts++ {
+		rec := do("10.0.0.1:1234")
+		status = rec.Code
+		if status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "20" {
+			t.Errorf("Retry-After = %q, want 20", rec.Header().Get("Retry-After"))
+		}
+	}
+	if status != http.StatusTooManyRequests || attempts != 4 {
+		t.Fatalf("got %d after %d invalid keys, want 429 after 4", status, attempts)
+	}
+	if keys.lookups != 3 {
+		t.Errorf("%d key lookups, want 3: the limited request should not reach the key store", keys.lookups)
+	}
+	if rec := do("10.0.0.2:1234"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("another IP: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Role grants a set of permissions to the callers it is assigned to
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// DefaultRole is the role of authenticated callers without assignment
+const DefaultRole = RoleViewer
+
+type Permission string
+
+const (
+	PermissionBreedsRead    Permission = "breeds:read"
+	PermissionBreedsWrite   Permission = "breeds:write"
+	PermissionPetsRead      Permission = "pets:read"
+	PermissionPetsWrite     Permission = "pets:write"
+	PermissionProductsRead  Permission = "products:read"
+	PermissionProductsWrite Permission = "products:write"
+	PermissionAdmin         Permission = "admin"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {PermissionBreedsRead, PermissionPetsRead, PermissionProductsRead},
+	RoleEditor: {
+		PermissionBreedsRead, PermissionBreedsWrite, PermissionPetsRead, PermissionPetsWrite,
+		PermissionProductsRead, PermissionProductsWrite,
+	},
+	RoleAdmin: {
+		PermissionBreedsRead, PermissionBreedsWrite, PermissionPetsRead, PermissionPetsWrite,
+		PermissionProductsRead, PermissionProductsWrite, PermissionAdmin,
+	},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether the caller of r is granted permission, every caller is when authentication is disabled
+func (a *App) allowed(r *http.Request, permission Permission) bool {
+	identity, ok := IdentityFromContext(r.Context())
+	return a.Auth == nil || (ok && identity.Role.Can(permission))
+}
+
+// require only lets through callers whose role grants permission
+//
+// Every caller is allowed when authentication is disabled
+func (a *App) require(permission Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.allowed(r, permission) {
+			handler(w, r)
+			return
+		}
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			writeProblem(w, http.StatusUnauthorized, "A valid API key or bearer token is required")
+			return
+		}
+		a.log(r).Warn("Permission denied", "permission", permission, "subject", identity.Subject, "role", identity.Role)
+		writeProblem(w, http.StatusForbidden, fmt.Sprintf("Role %s is not granted %s", identity.Role, permission))
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRoleBasedAccess(t *testing.T) {
+	do := newAuthTestRouter(t)
+	keys := map[Role]string{
+		RoleViewer: createTestAPIKey(t, do, ""),
+		RoleEditor: createTestAPIKey(t, do, RoleEditor),
+		RoleAdmin:  createTestAPIKey(t, do, RoleAdmin),
+	}
+	newBreed := `{"name":"beagle","species":"dog","average_weight":12000}`
+
+	tests := []struct {
+		role       Role
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{RoleViewer, http.MethodGet, "/v1/breeds", "", http.StatusOK},
+		{RoleViewer, http.MethodGet, "/v1/breeds/search?species=dog", "", http.StatusOK},
+		{RoleViewer, http.MethodPost, "/v1/breeds", newBreed, http.StatusForbidden},
+		{RoleViewer, http.MethodPut, "/v1/breeds/1", newBreed, http.StatusForbidden},
+		{RoleViewer, http.MethodDelete, "/v1/breeds/1", "", http.StatusForbidden},
+		{RoleViewer, http.MethodGet, "/admin/roles", "", http.StatusForbidden},
+		{RoleEditor, http.MethodPost, "/v1/breeds", newBreed, http.StatusCreated},
+		{RoleEditor, http.MethodDelete, "/v1/breeds/2", "", http.StatusNoContent},
+		{RoleEditor, http.MethodGet, "/admin/api-keys", "", http.StatusForbidden},
+		{RoleAdmin, http.MethodPut, "/v1/breeds/1", newBreed, http.StatusOK},
+		{RoleAdmin, http.MethodGet, "/admin/roles", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			rec := do(tt.method, tt.path, http.Header{"X-Api-Key": {keys[tt.role]}}, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusForbidden {
+				var problem Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Status != http.StatusForbidden {
+					t.Errorf("invalid problem body %s", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRoleAssignmentEndpoints(t *testing.T) {
+	do := newAuthTestRouter(t)
+	admin := http.Header{"Authorization": {"Bearer admin-secret"}}
+	key := createTestAPIKey(t, do, "")
+	caller := http.Header{"X-Api-Key": {key}}
+
+	if rec := do(http.MethodPost, "/v1/breeds", caller, `{"name":"beagle"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("viewer POST: status %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/admin/roles/api-key:1", admin, `{"role":"editor"}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign role: status %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/breeds", caller, `{"name":"beagle"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("editor POST: status %d", rec.Code)
+	}
+	checkProblem(t, do(http.MethodPut, "/admin/roles/api-key:1", admin, `{"role":"owner"}`), http.StatusBadRequest)
+	if rec := do(http.MethodDelete, "/admin/roles/api-key:1", admin, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("unassign role: status %d", rec.Code)
+	}
+	checkProblem(t, do(http.MethodDelete, "/admin/roles/api-key:1", admin, ""), http.StatusNotFound)
+	if rec := do(http.MethodPost, "/v1/breeds", caller, `{"name":"beagle"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("POST after unassign: status %d", rec.Code)
+	}
+}
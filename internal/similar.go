@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// similarityFactors weigh the weight, size and name scores of SimilarBreed, by default
+var similarityFactors = map[string]float64{"weight_factor": 0.6, "size_factor": 0.3, "name_factor": 0.1}
+
+// SimilarBreed is a breed of the same species as the one looked up, scores going from 0 to 1
+type SimilarBreed struct {
+	Breed       Breed   `json:"breed"`
+	Score       float64 `json:"score"`
+	WeightScore float64 `json:"weight_score"`
+	SizeScore   float64 `json:"size_score"`
+	NameScore   float64 `json:"name_score"`
+}
+
+// weightSimilarity is the ratio of the lightest average weight to the heaviest
+func weightSimilarity(a, b Breed) float64 {
+	lightest, heaviest := math.Min(a.AverageWeight, b.AverageWeight), math.Max(a.AverageWeight, b.AverageWeight)
+	if heaviest <= 0 {
+		return 1
+	}
+	return lightest / heaviest
+}
+
+// sizeIndex is the rank of a size class among those of species, false when it is not one of them
+func (c SizeClasses) sizeIndex(species, size string) (int, bool) {
+	for i, class := range c[species] {
+		if class.Name == size {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// sizeSimilarity decreases with the distance between the size classes of the breeds, and only tells whether their
+// sizes are equal for species without classes
+func (c SizeClasses) sizeSimilarity(a, b Breed) float64 {
+	i, iOK := c.sizeIndex(a.Species, a.PetSize)
+	j, jOK := c.sizeIndex(b.Species, b.PetSize)
+	if classes := c[a.Species]; iOK && jOK && len(classes) > 1 {
+		return 1 - math.Abs(float64(i-j))/float64(len(classes)-1)
+	}
+	if strings.EqualFold(a.PetSize, b.PetSize) {
+		return 1
+	}
+	return 0
+}
+
+// nameSimilarity is 1 less the edit distance between the lowercased names, relative to the longest
+func nameSimilarity(a, b string) float64 {
+	x, y := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	longest := max(len(x), len(y))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(x, y))/float64(longest)
+}
+
+// levenshtein counts the insertions, deletions and substitutions turning x into y
+func levenshtein(x, y []rune) int {
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(y)]
+}
+
+// rankSimilarBreeds scores the candidates against breed, leaving it out, and keeps the limit best ones, ties going
+// to the lowest id
+func rankSimilarBreeds(breed Breed, candidates []Breed, factors map[string]float64, classes SizeClasses, limit int) []SimilarBreed {
+	total := factors["weight_factor"] + factors["size_factor"] + factors["name_factor"]
+	similar := []SimilarBreed{}
+	for _, candidate := range candidates {
+		if candidate.ID == breed.ID || candidate.Species != breed.Species {
+			continue
+		}
+		match := SimilarBreed{
+			Breed:       candidate,
+			WeightScore: weightSimilarity(breed, candidate),
+			SizeScore:   classes.sizeSimilarity(breed, candidate),
+			NameScore:   nameSimilarity(breed.Name, candidate.Name),
+		}
+		match.Score = (factors["weight_factor"]*match.WeightScore + factors["size_factor"]*match.SizeScore +
+			factors["name_factor"]*match.NameScore) / total
+		similar = append(similar, match)
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].Breed.ID < similar[j].Breed.ID
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	for i := range similar {
+		similar[i].Score = roundTo(similar[i].Score, 3)
+		similar[i].WeightScore = roundTo(similar[i].WeightScore, 3)
+		similar[i].SizeScore = roundTo(similar[i].SizeScore, 3)
+		similar[i].NameScore = roundTo(similar[i].NameScore, 3)
+	}
+	return similar
+}
+
+// GetSimilarBreeds ranks the breeds of the same species by their closeness to the breed, for pets whose breed we do
+// not list
+//
+// `weight_factor`, `size_factor` and `name_factor` weigh the scores, 0.6, 0.3 and 0.1 by default, and `limit` sets
+// the number of breeds, 5 by default
+func (a *App) GetSimilarBreeds(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	query := r.URL.Query()
+
+	factors := map[string]float64{}
+	total := 0.0
+	for param, fallback := range similarityFactors {
+		factors[param] = fallback
+		if value := query.Get(param); value != "" {
+			factor, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(factor >= 0) || math.IsInf(factor, 0) {
+				writeProblem(w, http.StatusBadRequest, param+" must be a positive number")
+				return
+			}
+			factors[param] = factor
+		}
+		total += factors[param]
+	}
+	if total == 0 {
+		writeProblem(w, http.StatusBadRequest, "At least one of weight_factor, size_factor and name_factor must be above 0")
+		return
+	}
+	limit := 5
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			writeProblem(w, http.StatusBadRequest, "limit must be an integer between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.similar")
+	defer cancel()
+	breed, ok := a.routeBreed(w, r, ctx, id)
+	if !ok {
+		return
+	}
+	candidates, err := a.Store.Search(ctx, BreedFilter{Species: breed.Species})
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch breeds", "breed_id", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rankSimilarBreeds(breed, candidates, factors, a.SizeClasses, limit))
+}
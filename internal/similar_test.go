@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "Akita", b: "akita", want: 1},
+		{a: "akita", b: "akita inu", want: 1 - 4.0/9},
+		{a: "kitten", b: "sitting", want: 1 - 3.0/7},
+		{a: "", b: "", want: 1},
+		{a: "pug", b: "", want: 0},
+	}
+	for _, tt := range tests {
+		if got := nameSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSizeSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Breed
+		want float64
+	}{
+		{name: "same class", a: Breed{Species: "dog", PetSize: "large"}, b: Breed{Species: "dog", PetSize: "large"}, want: 1},
+		{name: "two classes apart", a: Breed{Species: "dog", PetSize: "small"}, b: Breed{Species: "dog", PetSize: "large"}, want: 0.5},
+		{name: "size not a class", a: Breed{Species: "dog", PetSize: "Unknown"}, b: Breed{Species: "dog", PetSize: "giant"}, want: 0},
+		{name: "species without classes", a: Breed{Species: "rabbit", PetSize: "small"}, b: Breed{Species: "rabbit", PetSize: "Small"}, want: 1},
+		{name: "species without classes, other size", a: Breed{Species: "rabbit", PetSize: "small"}, b: Breed{Species: "rabbit", PetSize: "tall"}, want: 0},
+	}
+	for _, tt := range tests {
+		if got := DefaultSizeClasses.sizeSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetSimilarBreeds(t *testing.T) {
+	breeds := append(append([]database_actions.BreedRecord{}, fixtureBreeds...),
+		database_actions.BreedRecord{Species: "dog", PetSize: "large", Name: "akita inu", WeightMin: 36000, WeightMax: 32000},
+		database_actions.BreedRecord{Species: "dog", PetSize: "small", Name: "pinscher", WeightMin: 5000, WeightMax: 4000},
+		database_actions.BreedRecord{Species: "rabbit", PetSize: "tall", Name: "flemish giant", WeightMin: 7000, WeightMax: 7000},
+	)
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       []string
+		wantScore  float64
+	}{
+		{name: "defaults", path: "/v1/breeds/2/similar", wantStatus: http.StatusOK, want: []string{"akita inu", "affenpinscher", "pinscher"}, wantScore: 0.866},
+		{name: "limit", path: "/v1/breeds/2/similar?limit=1", wantStatus: http.StatusOK, want: []string{"akita inu"}, wantScore: 0.866},
+		{name: "by name", path: "/v1/breeds/1/similar?weight_factor=0&size_factor=0&name_factor=1", wantStatus: http.StatusOK, want: []string{"pinscher", "akita inu", "akita"}, wantScore: 0.615},
+		{name: "by weight", path: "/v1/breeds/5/similar?weight_factor=1&size_factor=0&name_factor=0", wantStatus: http.StatusOK, want: []string{"affenpinscher", "akita inu", "akita"}, wantScore: 0.818},
+		{name: "alone in its species", path: "/v1/breeds/3/similar", wantStatus: http.StatusOK, want: []string{}},
+		{name: "unknown breed", path: "/v1/breeds/99/similar", wantStatus: http.StatusNotFound},
+		{name: "negative factor", path: "/v1/breeds/2/similar?size_factor=-1", wantStatus: http.StatusBadRequest},
+		{name: "no factor", path: "/v1/breeds/2/similar?weight_factor=0&size_factor=0&name_factor=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", path: "/v1/breeds/2/similar?limit=0", wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, NewMemoryBreedStore(breeds), &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []SimilarBreed
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, similar := range got {
+				names = append(names, similar.Breed.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("breeds = %v, want %v", names, tt.want)
+			}
+			if len(got) > 0 && got[0].Score != tt.wantScore {
+				t.Errorf("best score = %v, want %v", got[0].Score, tt.wantScore)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SizeClass holds the breeds whose average weight, in grams, is below MaxWeight, the last class of a species having
+// no bound
+type SizeClass struct {
+	Name      string  `json:"name"`
+	MaxWeight float64 `json:"max_weight,omitempty"`
+}
+
+// SizeClasses are the size classes of each species, from the lightest
+type SizeClasses map[string][]SizeClass
+
+// DefaultSizeClasses are used unless SIZE_CLASSES is set
+var DefaultSizeClasses = SizeClasses{
+	"dog": {{"toy", 4000}, {"small", 10000}, {"medium", 25000}, {"large", 45000}, {"giant", 0}},
+	"cat": {{"small", 3500}, {"medium", 5500}, {"large", 0}},
+}
+
+// ParseSizeClasses reads "<species>=<class><<max>,...,<class>;..." with weights in grams, e.g.
+// "cat=small<3500,medium<5500,large", the last class of each species having no bound
+func ParseSizeClasses(value string) (SizeClasses, error) {
+	classes := SizeClasses{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		species, list, found := strings.Cut(entry, "=")
+		species = strings.TrimSpace(species)
+		if !found || species == "" {
+			return nil, fmt.Errorf("invalid size classes %q, expected e.g. cat=small<3500,medium<5500,large", entry)
+		}
+		if _, ok := classes[species]; ok {
+			return nil, fmt.Errorf("invalid size classes: %s is listed twice", species)
+		}
+		items := strings.Split(list, ",")
+		for i, item := range items {
+			name, max, bounded := strings.Cut(strings.TrimSpace(item), "<")
+			class := SizeClass{Name: strings.TrimSpace(name)}
+			if class.Name == "" || bounded == (i == len(items)-1) {
+				return nil, fmt.Errorf("invalid size classes of %s: every class but the last one needs a <max weight", species)
+			}
+			if bounded {
+				weight, err := strconv.ParseFloat(strings.TrimSpace(max), 64)
+				if err != nil || weight <= 0 || (i > 0 && weight <= classes[species][i-1].MaxWeight) {
+					return nil, fmt.Errorf("invalid size classes of %s: %q is not a weight above the previous one", species, max)
+				}
+				class.MaxWeight = weight
+			}
+			classes[species] = append(classes[species], class)
+		}
+	}
+	return classes, nil
+}
+
+// Classify returns the size class of a breed of species whose average weight is weight, false when the species
+// has no classes
+func (c SizeClasses) Classify(species string, weight float64) (string, bool) {
+	classes := c[species]
+	for _, class := range classes {
+		if class.MaxWeight == 0 || weight < class.MaxWeight {
+			return class.Name, true
+		}
+	}
+	return "", false
+}
+
+// withPetSize sets the size class of the breed from its weights, fallback when its species has no classes
+func (a *App) withPetSize(breed Breed, fallback string) Breed {
+	breed.PetSize = fallback
+	if size, ok := a.SizeClasses.Classify(breed.Species, breed.AdultWeight("")); ok {
+		breed.PetSize = size
+	}
+	return breed
+}
+
+// SizeMismatch is a breed whose stored size is not the class of its weight
+type SizeMismatch struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Species       string  `json:"species"`
+	AverageWeight float64 `json:"average_weight"`
+	PetSize       string  `json:"pet_size"`
+	ExpectedSize  string  `json:"expected_size"`
+}
+
+// ListSizeMismatches returns the breeds, of `species` when given, whose size disagrees with their weight, by id
+//
+// Breeds of species without size classes are left out
+func (a *App) ListSizeMismatches(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.queryContext(r, "breeds.size_mismatches")
+	defer cancel()
+	breeds, err := a.Store.Search(ctx, BreedFilter{Species: r.URL.Query().Get("species")})
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch breeds")
+		return
+	}
+
+	mismatches := []SizeMismatch{}
+	for _, breed := range breeds {
+		expected, ok := a.SizeClasses.Classify(breed.Species, breed.AdultWeight(""))
+		if ok && expected != breed.PetSize {
+			mismatches = append(mismatches, SizeMismatch{
+				ID:            breed.ID,
+				Name:          breed.Name,
+				Species:       breed.Species,
+				AverageWeight: breed.AdultWeight(""),
+				PetSize:       breed.PetSize,
+				ExpectedSize:  expected,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mismatches)
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func TestParseSizeClasses(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SizeClasses
+		wantErr bool
+	}{
+		{value: "", want: SizeClasses{}},
+		{value: "cat=small<3500, medium<5500, large", want: SizeClasses{"cat": {{"small", 3500}, {"medium", 5500}, {"large", 0}}}},
+		{value: "dog=small<10000,large;rabbit=any", want: SizeClasses{"dog": {{"small", 10000}, {"large", 0}}, "rabbit": {{"any", 0}}}},
+		{value: "cat", wantErr: true},
+		{value: "cat=small<3500,large<5500", wantErr: true},
+		{value: "cat=small,large", wantErr: true},
+		{value: "cat=small<5500,medium<3500,large", wantErr: true},
+		{value: "cat=small<heavy,large", wantErr: true},
+		{value: "dog=small<10000,large;dog=toy<4000,giant", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSizeClasses(tt.value)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseSizeClasses(%q) = %+v, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		species string
+		weight  float64
+		want    string
+		wantOK  bool
+	}{
+		{species: "dog", weight: 2500, want: "toy", wantOK: true},
+		{species: "dog", weight: 4000, want: "small", wantOK: true},
+		{species: "dog", weight: 30000, want: "large", wantOK: true},
+		{species: "dog", weight: 70000, want: "giant", wantOK: true},
+		{species: "cat", weight: 6000, want: "large", wantOK: true},
+		{species: "rabbit", weight: 2000},
+	}
+	for _, tt := range tests {
+		got, ok := DefaultSizeClasses.Classify(tt.species, tt.weight)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Classify(%s, %v) = %s, %v, want %s, %v", tt.species, tt.weight, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBreedSizeDerivation(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		breedID  int
+		wantSize string
+	}{
+		{name: "create", method: http.MethodPost, path: "/v1/breeds", body: `{"name":"beagle","species":"dog","average_weight":12000}`, breedID: 4, wantSize: "medium"},
+		{name: "create of a species without classes", method: http.MethodPost, path: "/v1/breeds", body: `{"name":"rex","species":"rabbit","average_weight":3500}`, breedID: 4, wantSize: unknownPetSize},
+		{name: "update", method: http.MethodPut, path: "/v1/breeds/1", body: `{"name":"affenpinscher","species":"dog","average_weight":3000}`, breedID: 1, wantSize: "toy"},
+		{name: "update to a species without classes", method: http.MethodPut, path: "/v1/breeds/1", body: `{"name":"affenpinscher","species":"rabbit","average_weight":3000}`, breedID: 1, wantSize: "small"},
+		{name: "patch", method: http.MethodPatch, path: "/v1/breeds/2", body: `{"average_weight":60000}`, breedID: 2, wantSize: "giant"},
+		{name: "import", method: http.MethodPost, path: "/v1/breeds/import", body: "id,species,pet_size,name,male,female\n1,dog,tall,beagle,12000,11000\n", breedID: 4, wantSize: "medium"},
+		{name: "import of a species without classes", method: http.MethodPost, path: "/v1/breeds/import", body: "id,species,pet_size,name,male,female\n1,rabbit,small,rex,3000,3000\n", breedID: 4, wantSize: "small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp(charmLog.New(io.Discard))
+			app.Store = NewMemoryBreedStore(fixtureBreeds)
+			r := mux.NewRouter()
+			app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			switch tt.method {
+			case http.MethodPatch:
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+			case http.MethodPost, http.MethodPut:
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code >= 300 {
+				t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+			}
+
+			breed, err := app.Store.Get(context.Background(), tt.breedID)
+			if err != nil || breed.PetSize != tt.wantSize {
+				t.Errorf("pet_size = %q, %v, want %q", breed.PetSize, err, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestListSizeMismatches(t *testing.T) {
+	breeds := append([]database_actions.BreedRecord{
+		{Species: "dog", PetSize: "tall", Name: "mastiff", WeightMin: 80000, WeightMax: 70000},
+		{Species: "rabbit", PetSize: "tall", Name: "flemish giant", WeightMin: 7000, WeightMax: 7000},
+	}, fixtureBreeds...)
+	tests := []struct {
+		name  string
+		query string
+		want  []SizeMismatch
+	}{
+		{name: "all", want: []SizeMismatch{{ID: 1, Name: "mastiff", Species: "dog", AverageWeight: 75000, PetSize: "tall", ExpectedSize: "giant"}}},
+		{name: "species", query: "?species=cat", want: []SizeMismatch{}},
+	}
+
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, NewMemoryBreedStore(breeds), &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/size-mismatches"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+			}
+			var got []SizeMismatch
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
+
+func TestSeededBreedsHaveNoSizeMismatch(t *testing.T) {
+	seeds := map[string]func(t *testing.T) BreedStore{
+		"memory": func(t *testing.T) BreedStore {
+			records, err := database_actions.ReadBreedsFile("../breeds.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return NewMemoryBreedStore(database_actions.ClassifyBreeds(records, DefaultSizeClasses.Classify))
+		},
+		"sql": func(t *testing.T) BreedStore {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM breeds"); err != nil {
+				t.Fatal(err)
+			}
+			stats, err := database_actions.ImportBreeds(context.Background(), db, backend, "../breeds.csv", DefaultSizeClasses.Classify)
+			if err != nil || stats.Imported == 0 {
+				t.Fatalf("ImportBreeds() = %+v, %v", stats, err)
+			}
+			return NewSQLBreedStore(db, backend)
+		},
+	}
+
+	for name, seed := range seeds {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			r := newValidatedTestRouter(t, seed(t), &logs)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/size-mismatches", nil))
+			if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+				t.Errorf("status = %d, body = %s, want no mismatch", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
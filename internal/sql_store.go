@@ -0,0 +1,692 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+)
+
+const selectBreeds = `
+    SELECT 
+        id, 
+        name, 
+        species, 
+        pet_size,
+        weight_min,
+        weight_max,
+        (weight_min + weight_max) / 2.0 AS average_weight 
+    FROM breeds`
+
+type sqlBreedStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLBreedStore returns a BreedStore backed by the breeds table
+func NewSQLBreedStore(db *sql.DB, dialect database_actions.Backend) BreedStore {
+	return &sqlBreedStore{db: db, dialect: dialect}
+}
+
+func (s *sqlBreedStore) List(ctx context.Context) ([]Breed, error) {
+	return s.query(ctx, selectBreeds+" ORDER BY id")
+}
+
+func (s *sqlBreedStore) Get(ctx context.Context, id int) (Breed, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.Rebind(selectBreeds+" WHERE id = ?"), id)
+	breed, err := scanBreed(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Breed{}, ErrBreedNotFound
+	}
+	return breed, err
+}
+
+func (s *sqlBreedStore) Search(ctx context.Context, filter BreedFilter) ([]Breed, error) {
+	query := selectBreeds + " WHERE 1=1"
+	args := []interface{}{}
+
+	if filter.Species != "" {
+		query += " AND species = ?"
+		args = append(args, filter.Species)
+	}
+	if filter.MaxWeight != nil {
+		query += " AND (weight_min + weight_max) / 2.0 <= ?"
+		args = append(args, *filter.MaxWeight)
+	}
+	if filter.AfterID > 0 {
+		query += " AND id > ?"
+		args = append(args, filter.AfterID)
+	}
+	query += " ORDER BY id"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	return s.query(ctx, query, args...)
+}
+
+func (s *sqlBreedStore) Create(ctx context.Context, breed Breed) (Breed, error) {
+	id, err := s.dialect.InsertReturningID(ctx, s.db, `
+        INSERT INTO breeds (name, species, pet_size, weight_min, weight_max)
+        VALUES (?, ?, ?, ?, ?)`,
+		breed.Name, breed.Species, breed.PetSize, breed.WeightMin, breed.WeightMax)
+	if err != nil {
+		return Breed{}, err
+	}
+	breed.ID = int(id)
+	return breed, nil
+}
+
+func (s *sqlBreedStore) Update(ctx context.Context, breed Breed) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(`
+    UPDATE breeds 
+    SET name = ?, species = ?, pet_size = ?, weight_min = ?, weight_max = ? 
+    WHERE id = ?`),
+		breed.Name, breed.Species, breed.PetSize, breed.WeightMin, breed.WeightMax, breed.ID)
+	return err
+}
+
+func (s *sqlBreedStore) Delete(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM breeds WHERE id = ?"), id)
+	return err
+}
+
+func (s *sqlBreedStore) query(ctx context.Context, query string, args ...interface{}) ([]Breed, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var breeds []Breed
+	for rows.Next() {
+		breed, err := scanBreed(rows)
+		if err != nil {
+			return nil, err
+		}
+		breeds = append(breeds, breed)
+	}
+	return breeds, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBreed(row scanner) (Breed, error) {
+	var breed Breed
+	err := row.Scan(&breed.ID, &breed.Name, &breed.Species, &breed.PetSize, &breed.WeightMin, &breed.WeightMax, &breed.AverageWeight)
+	return breed, err
+}
+
+type sqlAPIKeyStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLAPIKeyStore returns an APIKeyStore backed by the api_keys table
+func NewSQLAPIKeyStore(db *sql.DB, dialect database_actions.Backend) APIKeyStore {
+	return &sqlAPIKeyStore{db: db, dialect: dialect}
+}
+
+const selectAPIKeys = "SELECT id, name, prefix, key_hash, created_at, revoked_at FROM api_keys"
+
+func (s *sqlAPIKeyStore) Create(ctx context.Context, key APIKey) (APIKey, error) {
+	id, err := s.dialect.InsertReturningID(ctx, s.db,
+		"INSERT INTO api_keys (name, prefix, key_hash, created_at) VALUES (?, ?, ?, ?)",
+		key.Name, key.Prefix, key.Hash, key.CreatedAt)
+	if err != nil {
+		return APIKey{}, err
+	}
+	key.ID = int(id)
+	return key, nil
+}
+
+func (s *sqlAPIKeyStore) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, selectAPIKeys+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *sqlAPIKeyStore) FindByHash(ctx context.Context, hash string) (APIKey, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.Rebind(selectAPIKeys+" WHERE key_hash = ?"), hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (s *sqlAPIKeyStore) Revoke(ctx context.Context, id int, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"), at, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, s.dialect.Rebind("SELECT 1 FROM api_keys WHERE id = ?"), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+func scanAPIKey(row scanner) (APIKey, error) {
+	var key APIKey
+	var revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.CreatedAt, &revokedAt)
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, err
+}
+
+type sqlRoleStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLRoleStore returns a RoleStore backed by the role_assignments table
+func NewSQLRoleStore(db *sql.DB, dialect database_actions.Backend) RoleStore {
+	return &sqlRoleStore{db: db, dialect: dialect}
+}
+
+func (s *sqlRoleStore) List(ctx context.Context) ([]RoleAssignment, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT subject, role, assigned_at FROM role_assignments ORDER BY subject")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []RoleAssignment{}
+	for rows.Next() {
+		var assignment RoleAssignment
+		if err := rows.Scan(&assignment.Subject, &assignment.Role, &assignment.AssignedAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+func (s *sqlRoleStore) Get(ctx context.Context, subject string) (RoleAssignment, error) {
+	assignment := RoleAssignment{Subject: subject}
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind("SELECT role, assigned_at FROM role_assignments WHERE subject = ?"), subject).
+		Scan(&assignment.Role, &assignment.AssignedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RoleAssignment{}, ErrRoleNotAssigned
+	}
+	return assignment, err
+}
+
+// Assign deletes then inserts in a transaction, upserts being written differently by each dialect
+func (s *sqlRoleStore) Assign(ctx context.Context, assignment RoleAssignment) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind("DELETE FROM role_assignments WHERE subject = ?"), assignment.Subject)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind("INSERT INTO role_assignments (subject, role, assigned_at) VALUES (?, ?, ?)"),
+		assignment.Subject, assignment.Role, assignment.AssignedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlRoleStore) Unassign(ctx context.Context, subject string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM role_assignments WHERE subject = ?"), subject)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return ErrRoleNotAssigned
+	}
+	return err
+}
+
+type sqlPetStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLPetStore returns a PetStore backed by the pets table, whose breed_id is cleared when the breed is deleted,
+// and by the pet_breeds table, whose shares are deleted with their breed
+func NewSQLPetStore(db *sql.DB, dialect database_actions.Backend) PetStore {
+	return &sqlPetStore{db: db, dialect: dialect}
+}
+
+const selectPets = `
+    SELECT id, name, species, breed_id, mixed_breed, birth_date, sex, neutered, current_weight, activity_level
+    FROM pets`
+
+func (s *sqlPetStore) List(ctx context.Context) ([]Pet, error) {
+	rows, err := s.db.QueryContext(ctx, selectPets+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pets := []Pet{}
+	for rows.Next() {
+		pet, err := scanPet(rows)
+		if err != nil {
+			return nil, err
+		}
+		pets = append(pets, pet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.shares(ctx, selectPetBreeds+" ORDER BY pet_id, share DESC, breed_id")
+	if err != nil {
+		return nil, err
+	}
+	for i := range pets {
+		pets[i].Breeds = shares[pets[i].ID]
+	}
+	return pets, nil
+}
+
+func (s *sqlPetStore) Get(ctx context.Context, id int) (Pet, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.Rebind(selectPets+" WHERE id = ?"), id)
+	pet, err := scanPet(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Pet{}, ErrPetNotFound
+	}
+	if err != nil {
+		return Pet{}, err
+	}
+
+	shares, err := s.shares(ctx, selectPetBreeds+" WHERE pet_id = ? ORDER BY share DESC, breed_id", id)
+	pet.Breeds = shares[id]
+	return pet, err
+}
+
+// Create inserts the pet and its breeds in a transaction
+func (s *sqlPetStore) Create(ctx context.Context, pet Pet) (Pet, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Pet{}, err
+	}
+	defer tx.Rollback()
+
+	id, err := s.dialect.InsertReturningID(ctx, tx, `
+        INSERT INTO pets (name, species, breed_id, mixed_breed, birth_date, sex, neutered, current_weight, activity_level)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		petArgs(pet)...)
+	if err != nil {
+		return Pet{}, err
+	}
+	pet.ID = int(id)
+	if err := s.insertShares(ctx, tx, pet); err != nil {
+		return Pet{}, err
+	}
+	return pet, tx.Commit()
+}
+
+// Update replaces the pet and its breeds in a transaction
+func (s *sqlPetStore) Update(ctx context.Context, pet Pet) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, s.dialect.Rebind(`
+    UPDATE pets
+    SET name = ?, species = ?, breed_id = ?, mixed_breed = ?, birth_date = ?, sex = ?, neutered = ?, current_weight = ?, activity_level = ?
+    WHERE id = ?`),
+		append(petArgs(pet), pet.ID)...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// MySQL does not count the rows left unchanged
+		var exists int
+		err = tx.QueryRowContext(ctx, s.dialect.Rebind("SELECT 1 FROM pets WHERE id = ?"), pet.ID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPetNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, s.dialect.Rebind("DELETE FROM pet_breeds WHERE pet_id = ?"), pet.ID); err != nil {
+		return err
+	}
+	if err := s.insertShares(ctx, tx, pet); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlPetStore) Delete(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM pets WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return ErrPetNotFound
+	}
+	return err
+}
+
+const selectPetBreeds = "SELECT pet_id, breed_id, share FROM pet_breeds"
+
+// shares returns the breeds of the pets selected by query, by pet id
+func (s *sqlPetStore) shares(ctx context.Context, query string, args ...interface{}) (map[int][]BreedShare, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := map[int][]BreedShare{}
+	for rows.Next() {
+		var petID int
+		var share BreedShare
+		if err := rows.Scan(&petID, &share.BreedID, &share.Share); err != nil {
+			return nil, err
+		}
+		shares[petID] = append(shares[petID], share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *sqlPetStore) insertShares(ctx context.Context, tx *sql.Tx, pet Pet) error {
+	for _, share := range pet.Breeds {
+		_, err := tx.ExecContext(ctx, s.dialect.Rebind("INSERT INTO pet_breeds (pet_id, breed_id, share) VALUES (?, ?, ?)"),
+			pet.ID, share.BreedID, share.Share)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func petArgs(pet Pet) []interface{} {
+	var birthDate sql.NullTime
+	if pet.BirthDate != nil {
+		birthDate = sql.NullTime{Time: pet.BirthDate.Time, Valid: true}
+	}
+	var breedID sql.NullInt64
+	if pet.BreedID != nil {
+		breedID = sql.NullInt64{Int64: int64(*pet.BreedID), Valid: true}
+	}
+	return []interface{}{pet.Name, pet.Species, breedID, pet.MixedBreed, birthDate, pet.Sex, pet.Neutered, pet.CurrentWeight, pet.ActivityLevel}
+}
+
+func scanPet(row scanner) (Pet, error) {
+	var pet Pet
+	var breedID sql.NullInt64
+	var birthDate sql.NullTime
+	err := row.Scan(&pet.ID, &pet.Name, &pet.Species, &breedID, &pet.MixedBreed, &birthDate, &pet.Sex, &pet.Neutered,
+		&pet.CurrentWeight, &pet.ActivityLevel)
+	if breedID.Valid {
+		id := int(breedID.Int64)
+		pet.BreedID = &id
+	}
+	if birthDate.Valid {
+		day := NewDate(birthDate.Time)
+		pet.BirthDate = &day
+	}
+	return pet, err
+}
+
+type sqlProductStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLProductStore returns a ProductStore backed by the products table, whose lists are comma-separated
+func NewSQLProductStore(db *sql.DB, dialect database_actions.Backend) ProductStore {
+	return &sqlProductStore{db: db, dialect: dialect}
+}
+
+const selectProducts = "SELECT id, name, kind, kcal_per_kg, species, pet_sizes, life_stages FROM products"
+
+func (s *sqlProductStore) List(ctx context.Context, filter ProductFilter) ([]Product, error) {
+	query := selectProducts + " WHERE 1=1"
+	args := []interface{}{}
+	if filter.Species != "" {
+		query += " AND species = ?"
+		args = append(args, filter.Species)
+	}
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+func (s *sqlProductStore) Get(ctx context.Context, id int) (Product, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.Rebind(selectProducts+" WHERE id = ?"), id)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, ErrProductNotFound
+	}
+	return product, err
+}
+
+func (s *sqlProductStore) Create(ctx context.Context, product Product) (Product, error) {
+	id, err := s.dialect.InsertReturningID(ctx, s.db,
+		"INSERT INTO products (name, kind, kcal_per_kg, species, pet_sizes, life_stages) VALUES (?, ?, ?, ?, ?, ?)",
+		productArgs(product)...)
+	if err != nil {
+		return Product{}, err
+	}
+	product.ID = int(id)
+	return product, nil
+}
+
+func (s *sqlProductStore) Update(ctx context.Context, product Product) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(
+		"UPDATE products SET name = ?, kind = ?, kcal_per_kg = ?, species = ?, pet_sizes = ?, life_stages = ? WHERE id = ?"),
+		append(productArgs(product), product.ID)...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	// MySQL does not count the rows left unchanged
+	var exists int
+	err = s.db.QueryRowContext(ctx, s.dialect.Rebind("SELECT 1 FROM products WHERE id = ?"), product.ID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+func (s *sqlProductStore) Delete(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM products WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+func productArgs(product Product) []interface{} {
+	stages := make([]string, len(product.LifeStages))
+	for i, stage := range product.LifeStages {
+		stages[i] = string(stage)
+	}
+	return []interface{}{product.Name, product.Kind, product.KcalPerKg, product.Species,
+		strings.Join(product.PetSizes, ","), strings.Join(stages, ",")}
+}
+
+func scanProduct(row scanner) (Product, error) {
+	var product Product
+	var sizes, stages string
+	err := row.Scan(&product.ID, &product.Name, &product.Kind, &product.KcalPerKg, &product.Species, &sizes, &stages)
+	product.PetSizes = splitList(sizes)
+	for _, stage := range splitList(stages) {
+		product.LifeStages = append(product.LifeStages, LifeStage(stage))
+	}
+	return product.withEmptyLists(), err
+}
+
+// splitList splits a comma-separated column, an empty one being an empty list
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+type sqlGrowthCurveStore struct {
+	db      *sql.DB
+	dialect database_actions.Backend
+}
+
+// NewSQLGrowthCurveStore returns a GrowthCurveStore backed by the growth_curves and breed_growth_curves tables, the
+// curve of a breed being deleted with it
+func NewSQLGrowthCurveStore(db *sql.DB, dialect database_actions.Backend) GrowthCurveStore {
+	return &sqlGrowthCurveStore{db: db, dialect: dialect}
+}
+
+func (s *sqlGrowthCurveStore) List(ctx context.Context) ([]GrowthCurve, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT species, pet_size, birth_share, adult_weeks, spread FROM growth_curves ORDER BY species, pet_size")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	curves := []GrowthCurve{}
+	for rows.Next() {
+		var curve GrowthCurve
+		if err := rows.Scan(&curve.Species, &curve.PetSize, &curve.BirthShare, &curve.AdultWeeks, &curve.Spread); err != nil {
+			return nil, err
+		}
+		curves = append(curves, curve)
+	}
+	return curves, rows.Err()
+}
+
+func (s *sqlGrowthCurveStore) Get(ctx context.Context, species, petSize string) (GrowthCurve, error) {
+	curve := GrowthCurve{Species: species, PetSize: petSize}
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(
+		"SELECT birth_share, adult_weeks, spread FROM growth_curves WHERE species = ? AND pet_size = ?"), species, petSize).
+		Scan(&curve.BirthShare, &curve.AdultWeeks, &curve.Spread)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GrowthCurve{}, ErrGrowthCurveNotFound
+	}
+	return curve, err
+}
+
+// Set deletes then inserts in a transaction, as sqlRoleStore.Assign
+func (s *sqlGrowthCurveStore) Set(ctx context.Context, curve GrowthCurve) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind("DELETE FROM growth_curves WHERE species = ? AND pet_size = ?"),
+		curve.Species, curve.PetSize)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind(
+		"INSERT INTO growth_curves (species, pet_size, birth_share, adult_weeks, spread) VALUES (?, ?, ?, ?, ?)"),
+		curve.Species, curve.PetSize, curve.BirthShare, curve.AdultWeeks, curve.Spread)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlGrowthCurveStore) GetBreed(ctx context.Context, breedID int) (GrowthCurve, error) {
+	curve := GrowthCurve{BreedID: breedID}
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(
+		"SELECT birth_share, adult_weeks, spread FROM breed_growth_curves WHERE breed_id = ?"), breedID).
+		Scan(&curve.BirthShare, &curve.AdultWeeks, &curve.Spread)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GrowthCurve{}, ErrGrowthCurveNotFound
+	}
+	return curve, err
+}
+
+// SetBreed deletes then inserts in a transaction, as sqlRoleStore.Assign
+func (s *sqlGrowthCurveStore) SetBreed(ctx context.Context, curve GrowthCurve) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind("DELETE FROM breed_growth_curves WHERE breed_id = ?"), curve.BreedID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.dialect.Rebind(
+		"INSERT INTO breed_growth_curves (breed_id, birth_share, adult_weeks, spread) VALUES (?, ?, ?, ?)"),
+		curve.BreedID, curve.BirthShare, curve.AdultWeeks, curve.Spread)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlGrowthCurveStore) DeleteBreed(ctx context.Context, breedID int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM breed_growth_curves WHERE breed_id = ?"), breedID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return ErrGrowthCurveNotFound
+	}
+	return err
+}
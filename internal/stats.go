@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// statsPercentiles are the percentiles of WeightStats
+var statsPercentiles = []int{10, 25, 50, 75, 90}
+
+// BreedStats is the answer of GetBreedStats, weights are average adult weights in grams
+type BreedStats struct {
+	Count   int            `json:"count"`
+	Species []SpeciesCount `json:"species"`
+	// Weight is absent when no breed matches
+	Weight   *WeightStats `json:"weight,omitempty"`
+	Heaviest []Breed      `json:"heaviest"`
+	Lightest []Breed      `json:"lightest"`
+}
+
+// SpeciesCount counts the breeds of a species, and of each of its sizes
+type SpeciesCount struct {
+	Species string      `json:"species"`
+	Count   int         `json:"count"`
+	Sizes   []SizeCount `json:"sizes"`
+}
+
+type SizeCount struct {
+	PetSize string `json:"pet_size"`
+	Count   int    `json:"count"`
+}
+
+// WeightStats describes the distribution of the weights
+type WeightStats struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	// Percentiles are keyed p10, p25, p50, p75 and p90
+	Percentiles map[string]float64 `json:"percentiles"`
+	Histogram   []HistogramBucket  `json:"histogram"`
+}
+
+// HistogramBucket counts the weights from From, included, to To, excluded but for the last bucket
+type HistogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// computeBreedStats aggregates breeds, with histogram buckets of equal width and top breeds at each end
+func computeBreedStats(breeds []Breed, buckets, top int) BreedStats {
+	stats := BreedStats{Count: len(breeds), Species: []SpeciesCount{}, Heaviest: []Breed{}, Lightest: []Breed{}}
+
+	counts := map[string]map[string]int{}
+	for _, breed := range breeds {
+		if counts[breed.Species] == nil {
+			counts[breed.Species] = map[string]int{}
+		}
+		counts[breed.Species][breed.PetSize]++
+	}
+	for species, sizes := range counts {
+		count := SpeciesCount{Species: species, Sizes: []SizeCount{}}
+		for size, n := range sizes {
+			count.Count += n
+			count.Sizes = append(count.Sizes, SizeCount{PetSize: size, Count: n})
+		}
+		sort.Slice(count.Sizes, func(i, j int) bool { return count.Sizes[i].PetSize < count.Sizes[j].PetSize })
+		stats.Species = append(stats.Species, count)
+	}
+	sort.Slice(stats.Species, func(i, j int) bool { return stats.Species[i].Species < stats.Species[j].Species })
+
+	if len(breeds) == 0 {
+		return stats
+	}
+
+	byWeight := append([]Breed(nil), breeds...)
+	sort.SliceStable(byWeight, func(i, j int) bool { return byWeight[i].AverageWeight < byWeight[j].AverageWeight })
+	weights := make([]float64, len(byWeight))
+	total := 0.0
+	for i, breed := range byWeight {
+		weights[i] = breed.AverageWeight
+		total += breed.AverageWeight
+	}
+	stats.Weight = &WeightStats{
+		Min:         weights[0],
+		Max:         weights[len(weights)-1],
+		Mean:        roundTo(total/float64(len(weights)), 1),
+		Percentiles: map[string]float64{},
+		Histogram:   histogram(weights, buckets),
+	}
+	for _, p := range statsPercentiles {
+		stats.Weight.Percentiles["p"+strconv.Itoa(p)] = roundTo(percentile(weights, float64(p)), 1)
+	}
+
+	n := min(top, len(byWeight))
+	stats.Lightest = append(stats.Lightest, byWeight[:n]...)
+	for i := len(byWeight) - 1; i >= len(byWeight)-n; i-- {
+		stats.Heaviest = append(stats.Heaviest, byWeight[i])
+	}
+	return stats
+}
+
+// percentile interpolates linearly between the closest ranks of the sorted weights
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// histogram splits the range of the sorted weights in buckets of equal width, a single one when they are equal
+func histogram(sorted []float64, buckets int) []HistogramBucket {
+	lowest, highest := sorted[0], sorted[len(sorted)-1]
+	if lowest == highest {
+		return []HistogramBucket{{From: lowest, To: highest, Count: len(sorted)}}
+	}
+
+	width := (highest - lowest) / float64(buckets)
+	histogram := make([]HistogramBucket, buckets)
+	for i := range histogram {
+		histogram[i].From = roundTo(lowest+float64(i)*width, 1)
+		histogram[i].To = roundTo(lowest+float64(i+1)*width, 1)
+	}
+	histogram[buckets-1].To = highest
+	for _, weight := range sorted {
+		i := min(int((weight-lowest)/width), buckets-1)
+		histogram[i].Count++
+	}
+	return histogram
+}
+
+// GetBreedStats aggregates the breeds matching the `species` and `weight` parameters of SearchBreeds
+//
+// `buckets` sets the number of histogram buckets, 10 by default, and `top` the number of heaviest and lightest
+// breeds, 5 by default
+func (a *App) GetBreedStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := BreedFilter{Species: query.Get("species")}
+	if value := query.Get("weight"); value != "" {
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			writeProblem(w, http.StatusBadRequest, "weight must be a positive number of grams")
+			return
+		}
+		filter.MaxWeight = &weight
+	}
+	buckets, top := 10, 5
+	for _, setting := range []struct {
+		param string
+		dest  *int
+	}{{"buckets", &buckets}, {"top", &top}} {
+		value := query.Get(setting.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			writeProblem(w, http.StatusBadRequest, setting.param+" must be an integer between 1 and 100")
+			return
+		}
+		*setting.dest = n
+	}
+
+	ctx, cancel := a.queryContext(r, "breeds.stats")
+	defer cancel()
+	breeds, err := a.Store.Search(ctx, filter)
+	if err != nil {
+		a.storeFailed(w, r, ctx, err, "Failed to fetch breeds")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(computeBreedStats(breeds, buckets, top))
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	weights := []float64{3500, 5500, 40000}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{p: 0, want: 3500},
+		{p: 10, want: 3900},
+		{p: 50, want: 5500},
+		{p: 90, want: 33100},
+		{p: 100, want: 40000},
+	}
+	for _, tt := range tests {
+		if got := percentile(weights, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]float64{1200}, 50); got != 1200 {
+		t.Errorf("percentile of a single weight = %v, want 1200", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		buckets int
+		want    []HistogramBucket
+	}{
+		{name: "buckets", weights: []float64{0, 10, 49, 50, 100}, buckets: 2, want: []HistogramBucket{{0, 50, 3}, {50, 100, 2}}},
+		{name: "empty buckets", weights: []float64{0, 100}, buckets: 4, want: []HistogramBucket{{0, 25, 1}, {25, 50, 0}, {50, 75, 0}, {75, 100, 1}}},
+		{name: "equal weights", weights: []float64{300, 300}, buckets: 10, want: []HistogramBucket{{300, 300, 2}}},
+	}
+	for _, tt := range tests {
+		if got := histogram(tt.weights, tt.buckets); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetBreedStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantStatus   int
+		wantCount    int
+		wantSpecies  []SpeciesCount
+		wantWeight   *WeightStats
+		wantHeaviest []string
+		wantLightest []string
+	}{
+		{
+			name:       "all",
+			query:      "?buckets=2&top=2",
+			wantStatus: http.StatusOK,
+			wantCount:  3,
+			wantSpecies: []SpeciesCount{
+				{Species: "cat", Count: 1, Sizes: []SizeCount{{"medium", 1}}},
+				{Species: "dog", Count: 2, Sizes: []SizeCount{{"large", 1}, {"small", 1}}},
+			},
+			wantWeight: &WeightStats{
+				Min: 3500, Max: 40000, Mean: 16333.3,
+				Percentiles: map[string]float64{"p10": 3900, "p25": 4500, "p50": 5500, "p75": 22750, "p90": 33100},
+				Histogram:   []HistogramBucket{{3500, 21750, 2}, {21750, 40000, 1}},
+			},
+			wantHeaviest: []string{"akita", "affenpinscher"},
+			wantLightest: []string{"abyssinian", "affenpinscher"},
+		},
+		{
+			name:        "filtered",
+			query:       "?species=dog&weight=10000",
+			wantStatus:  http.StatusOK,
+			wantCount:   1,
+			wantSpecies: []SpeciesCount{{Species: "dog", Count: 1, Sizes: []SizeCount{{"small", 1}}}},
+			wantWeight: &WeightStats{
+				Min: 5500, Max: 5500, Mean: 5500,
+				Percentiles: map[string]float64{"p10": 5500, "p25": 5500, "p50": 5500, "p75": 5500, "p90": 5500},
+				Histogram:   []HistogramBucket{{5500, 5500, 1}},
+			},
+			wantHeaviest: []string{"affenpinscher"},
+			wantLightest: []string{"affenpinscher"},
+		},
+		{name: "no match", query: "?species=rabbit", wantStatus: http.StatusOK, wantSpecies: []SpeciesCount{}, wantHeaviest: []string{}, wantLightest: []string{}},
+		{name: "invalid buckets", query: "?buckets=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid top", query: "?top=many", wantStatus: http.StatusBadRequest},
+		{name: "invalid weight", query: "?weight=abc", wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, NewMemoryBreedStore(fixtureBreeds), &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/stats"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got BreedStats
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Count != tt.wantCount || !reflect.DeepEqual(got.Species, tt.wantSpecies) {
+				t.Errorf("count = %d, species = %+v, want %d, %+v", got.Count, got.Species, tt.wantCount, tt.wantSpecies)
+			}
+			if !reflect.DeepEqual(got.Weight, tt.wantWeight) {
+				t.Errorf("weight = %+v, want %+v", got.Weight, tt.wantWeight)
+			}
+			if names := breedNames(got.Heaviest); !reflect.DeepEqual(names, tt.wantHeaviest) {
+				t.Errorf("heaviest = %v, want %v", names, tt.wantHeaviest)
+			}
+			if names := breedNames(got.Lightest); !reflect.DeepEqual(names, tt.wantLightest) {
+				t.Errorf("lightest = %v, want %v", names, tt.wantLightest)
+			}
+		})
+	}
+	if strings.Contains(logs.String(), "Response does not match") {
+		t.Errorf("response flagged as not matching the spec: %s", logs.String())
+	}
+}
+
+// TestBreedStatsParameters checks the handler itself rejects invalid parameters, without the validator
+func TestBreedStatsParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantDetail string
+	}{
+		{name: "invalid weight", query: "?weight=abc", wantDetail: "weight must be a positive number of grams"},
+		{name: "negative weight", query: "?weight=-1", wantDetail: "weight must be a positive number of grams"},
+		{name: "invalid buckets and top", query: "?top=0&buckets=0", wantDetail: "buckets must be an integer between 1 and 100"},
+		{name: "invalid top", query: "?buckets=3&top=101", wantDetail: "top must be an integer between 1 and 100"},
+	}
+
+	r := newTestRouter(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds/stats"+tt.query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body: %s)", rec.Code, rec.Body.String())
+			}
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, %v, want %q", problem.Detail, err, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func breedNames(breeds []Breed) []string {
+	names := []string{}
+	for _, breed := range breeds {
+		names = append(names, breed.Name)
+	}
+	return names
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrBreedNotFound is returned by a BreedStore when no breed has the requested id
+	ErrBreedNotFound = errors.New("breed not found")
+	// ErrAPIKeyNotFound is returned by an APIKeyStore when no key matches
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrRoleNotAssigned is returned by a RoleStore when the subject has no role
+	ErrRoleNotAssigned = errors.New("role not assigned")
+	// ErrPetNotFound is returned by a PetStore when no pet has the requested id
+	ErrPetNotFound = errors.New("pet not found")
+	// ErrProductNotFound is returned by a ProductStore when no product has the requested id
+	ErrProductNotFound = errors.New("product not found")
+	// ErrGrowthCurveNotFound is returned by a GrowthCurveStore when no curve is stored for the category or breed
+	ErrGrowthCurveNotFound = errors.New("growth curve not found")
+)
+
+// BreedStore persists breeds, the API runs either on SQL (see NewSQLBreedStore) or in memory (see NewMemoryBreedStore)
+type BreedStore interface {
+	List(ctx context.Context) ([]Breed, error)
+	Get(ctx context.Context, id int) (Breed, error)
+	Search(ctx context.Context, filter BreedFilter) ([]Breed, error)
+	// Create returns the breed with its generated id
+	Create(ctx context.Context, breed Breed) (Breed, error)
+	Update(ctx context.Context, breed Breed) error
+	Delete(ctx context.Context, id int) error
+}
+
+// BreedFilter narrows a search, zero values are ignored
+//
+// Breeds are ordered by id, AfterID and Limit page through them
+type BreedFilter struct {
+	Species   string
+	MaxWeight *float64
+	AfterID   int
+	Limit     int
+}
+
+func (f BreedFilter) match(breed Breed) bool {
+	if f.Species != "" && breed.Species != f.Species {
+		return false
+	}
+	if f.MaxWeight != nil && breed.AverageWeight > *f.MaxWeight {
+		return false
+	}
+	if breed.ID <= f.AfterID {
+		return false
+	}
+	return true
+}
+
+// APIKey identifies a client of the API, only the SHA-256 of the secret is stored
+type APIKey struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyStore persists API keys, see NewSQLAPIKeyStore and NewMemoryAPIKeyStore
+type APIKeyStore interface {
+	// Create returns the key with its generated id
+	Create(ctx context.Context, key APIKey) (APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	// FindByHash also returns revoked keys
+	FindByHash(ctx context.Context, hash string) (APIKey, error)
+	// Revoke is a no-op for keys already revoked
+	Revoke(ctx context.Context, id int, at time.Time) error
+}
+
+// RoleAssignment grants Role to the caller identified by Subject, see Identity
+type RoleAssignment struct {
+	Subject    string    `json:"subject"`
+	Role       Role      `json:"role"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// RoleStore persists role assignments, see NewSQLRoleStore and NewMemoryRoleStore
+type RoleStore interface {
+	List(ctx context.Context) ([]RoleAssignment, error)
+	Get(ctx context.Context, subject string) (RoleAssignment, error)
+	// Assign replaces the role of the subject
+	Assign(ctx context.Context, assignment RoleAssignment) error
+	Unassign(ctx context.Context, subject string) error
+}
+
+// PetStore persists pets, see NewSQLPetStore and NewMemoryPetStore
+type PetStore interface {
+	List(ctx context.Context) ([]Pet, error)
+	Get(ctx context.Context, id int) (Pet, error)
+	// Create returns the pet with its generated id
+	Create(ctx context.Context, pet Pet) (Pet, error)
+	Update(ctx context.Context, pet Pet) error
+	Delete(ctx context.Context, id int) error
+}
+
+// ProductStore persists the food catalog, see NewSQLProductStore and NewMemoryProductStore
+type ProductStore interface {
+	// List returns the products matching filter, by id
+	List(ctx context.Context, filter ProductFilter) ([]Product, error)
+	Get(ctx context.Context, id int) (Product, error)
+	// Create returns the product with its generated id
+	Create(ctx context.Context, product Product) (Product, error)
+	Update(ctx context.Context, product Product) error
+	Delete(ctx context.Context, id int) error
+}
+
+// ProductFilter narrows a product listing, zero values are ignored
+type ProductFilter struct {
+	Species string
+}
+
+// GrowthCurveStore persists the growth curves of the size categories and of the breeds overriding them, see
+// NewSQLGrowthCurveStore and NewMemoryGrowthCurveStore
+type GrowthCurveStore interface {
+	// List returns the curves of the size categories, by species and size
+	List(ctx context.Context) ([]GrowthCurve, error)
+	Get(ctx context.Context, species, petSize string) (GrowthCurve, error)
+	// Set replaces the curve of the size category of curve
+	Set(ctx context.Context, curve GrowthCurve) error
+	GetBreed(ctx context.Context, breedID int) (GrowthCurve, error)
+	// SetBreed replaces the curve of the breed of curve
+	SetBreed(ctx context.Context, curve GrowthCurve) error
+	DeleteBreed(ctx context.Context, breedID int) error
+}
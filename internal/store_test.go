@@ -0,0 +1,497 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+)
+
+// newSQLTestDB migrates a fresh SQLite file, or the TEST_DB_BACKEND/TEST_DB_DSN database when set
+func newSQLTestDB(t *testing.T) (*sql.DB, database_actions.Backend) {
+	t.Helper()
+	backend, err := database_actions.ParseBackend(os.Getenv("TEST_DB_BACKEND"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := os.Getenv("TEST_DB_DSN")
+	if os.Getenv("TEST_DB_BACKEND") == "" {
+		backend = database_actions.BackendSQLite
+		dsn = "file:" + filepath.Join(t.TempDir(), "core.db") + "?_pragma=foreign_keys(1)"
+	}
+
+	db, err := sql.Open(backend.DriverName(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database_actions.InitMigrator(db, backend); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database_actions.RunMigrate("up", 0); err != nil {
+		t.Fatal(err)
+	}
+	return db, backend
+}
+
+func newSQLTestStore(t *testing.T) BreedStore {
+	t.Helper()
+	db, backend := newSQLTestDB(t)
+	if _, err := db.Exec("DELETE FROM breeds"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSQLBreedStore(db, backend)
+	for _, record := range fixtureBreeds {
+		_, err := store.Create(context.Background(), Breed{
+			Name:      record.Name,
+			Species:   record.Species,
+			PetSize:   record.PetSize,
+			WeightMin: record.WeightMin,
+			WeightMax: record.WeightMax,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestBreedStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) BreedStore{
+		"memory": func(t *testing.T) BreedStore { return NewMemoryBreedStore(fixtureBreeds) },
+		"sql":    newSQLTestStore,
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testBreedStore(t, newStore(t))
+		})
+	}
+}
+
+func testBreedStore(t *testing.T, store BreedStore) {
+	ctx := context.Background()
+
+	breeds, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breeds) != len(fixtureBreeds) {
+		t.Fatalf("List returned %d breeds, want %d", len(breeds), len(fixtureBreeds))
+	}
+	first := breeds[0]
+	if first.Name != "affenpinscher" || first.PetSize != "small" || first.AverageWeight != 5500 {
+		t.Errorf("first breed = %+v", first)
+	}
+
+	maxWeight := 6000.0
+	found, err := store.Search(ctx, BreedFilter{Species: "dog", MaxWeight: &maxWeight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Name != "affenpinscher" {
+		t.Errorf("Search returned %+v, want affenpinscher only", found)
+	}
+
+	page, err := store.Search(ctx, BreedFilter{AfterID: first.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != breeds[1].ID {
+		t.Errorf("Search after %d limited to 1 returned %+v, want %+v", first.ID, page, breeds[1])
+	}
+
+	created, err := store.Create(ctx, Breed{Name: "beagle", Species: "dog", PetSize: "medium", WeightMin: 11000, WeightMax: 13000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "beagle" || got.AverageWeight != 12000 {
+		t.Errorf("Get(%d) = %+v", created.ID, got)
+	}
+
+	got.Name = "harrier"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "harrier" {
+		t.Errorf("Update did not rename the breed: %+v", got)
+	}
+
+	if err := store.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, created.ID); !errors.Is(err, ErrBreedNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrBreedNotFound", err)
+	}
+}
+
+func TestAPIKeyStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) APIKeyStore{
+		"memory": func(t *testing.T) APIKeyStore { return NewMemoryAPIKeyStore() },
+		"sql": func(t *testing.T) APIKeyStore {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM api_keys"); err != nil {
+				t.Fatal(err)
+			}
+			return NewSQLAPIKeyStore(db, backend)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			now := time.Now().UTC().Truncate(time.Second)
+
+			created, err := store.Create(ctx, APIKey{Name: "storefront", Prefix: "jpk_abc", Hash: "hash", CreatedAt: now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, err := store.FindByHash(ctx, "hash")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found.ID != created.ID || found.Name != "storefront" || !found.CreatedAt.Equal(now) || found.RevokedAt != nil {
+				t.Errorf("FindByHash = %+v", found)
+			}
+			if _, err := store.FindByHash(ctx, "other"); !errors.Is(err, ErrAPIKeyNotFound) {
+				t.Errorf("FindByHash(unknown) returned %v", err)
+			}
+
+			if err := store.Revoke(ctx, created.ID, now); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Revoke(ctx, created.ID, now.Add(time.Hour)); err != nil {
+				t.Errorf("revoking twice returned %v", err)
+			}
+			if err := store.Revoke(ctx, created.ID+1, now); !errors.Is(err, ErrAPIKeyNotFound) {
+				t.Errorf("Revoke(unknown) returned %v", err)
+			}
+
+			keys, err := store.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 1 || keys[0].RevokedAt == nil || !keys[0].RevokedAt.Equal(now) {
+				t.Errorf("List = %+v, want the key revoked at %s", keys, now)
+			}
+		})
+	}
+}
+
+func TestRoleStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) RoleStore{
+		"memory": func(t *testing.T) RoleStore { return NewMemoryRoleStore() },
+		"sql": func(t *testing.T) RoleStore {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM role_assignments"); err != nil {
+				t.Fatal(err)
+			}
+			return NewSQLRoleStore(db, backend)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			now := time.Now().UTC().Truncate(time.Second)
+
+			if _, err := store.Get(ctx, "alice"); !errors.Is(err, ErrRoleNotAssigned) {
+				t.Errorf("Get(unassigned) returned %v", err)
+			}
+			for _, role := range []Role{RoleViewer, RoleEditor} {
+				if err := store.Assign(ctx, RoleAssignment{Subject: "alice", Role: role, AssignedAt: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			assignment, err := store.Get(ctx, "alice")
+			if err != nil || assignment.Role != RoleEditor {
+				t.Errorf("Get = %+v, %v, want editor", assignment, err)
+			}
+			assignments, err := store.List(ctx)
+			if err != nil || len(assignments) != 1 {
+				t.Errorf("List = %+v, %v", assignments, err)
+			}
+			if err := store.Unassign(ctx, "alice"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Unassign(ctx, "alice"); !errors.Is(err, ErrRoleNotAssigned) {
+				t.Errorf("Unassign twice returned %v", err)
+			}
+		})
+	}
+}
+
+func TestPetStores(t *testing.T) {
+	type stores struct {
+		pets   PetStore
+		breeds BreedStore
+	}
+	newStores := map[string]func(t *testing.T) stores{
+		"memory": func(t *testing.T) stores {
+			breeds := NewMemoryBreedStore(fixtureBreeds)
+			return stores{pets: NewMemoryPetStore(breeds), breeds: breeds}
+		},
+		"sql": func(t *testing.T) stores {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM pets"); err != nil {
+				t.Fatal(err)
+			}
+			return stores{pets: NewSQLPetStore(db, backend), breeds: NewSQLBreedStore(db, backend)}
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+			breed, err := s.breeds.Create(ctx, Breed{Name: "beagle", Species: "dog", PetSize: "medium", WeightMin: 12000, WeightMax: 11000})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			birthDate := NewDate(time.Date(2021, time.March, 14, 0, 0, 0, 0, time.UTC))
+			pet := Pet{Name: "Rex", Species: "dog", BreedID: &breed.ID, BirthDate: &birthDate, Sex: SexMale, Neutered: true, CurrentWeight: 11500, ActivityLevel: ActivityHigh}
+			created, err := s.pets.Create(ctx, pet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.pets.Get(ctx, created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != "Rex" || got.BreedID == nil || *got.BreedID != breed.ID || got.BirthDate == nil || !got.BirthDate.Equal(birthDate.Time) ||
+				got.Sex != SexMale || !got.Neutered || got.CurrentWeight != 11500 || got.ActivityLevel != ActivityHigh {
+				t.Errorf("Get = %+v", got)
+			}
+
+			poodle, err := s.breeds.Create(ctx, Breed{Name: "poodle", Species: "dog", PetSize: "medium", WeightMin: 25000, WeightMax: 20000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cross := created
+			cross.MixedBreed = true
+			cross.Breeds = []BreedShare{{BreedID: poodle.ID, Share: 60}, {BreedID: breed.ID, Share: 40}}
+			if err := s.pets.Update(ctx, cross); err != nil {
+				t.Fatal(err)
+			}
+			got, err = s.pets.Get(ctx, created.ID)
+			if err != nil || len(got.Breeds) != 2 || got.Breeds[0] != cross.Breeds[0] || got.Breeds[1] != cross.Breeds[1] {
+				t.Errorf("Get after Update with breeds = %+v, %v", got, err)
+			}
+
+			mixed := Pet{ID: created.ID, Name: "Rex", Species: "dog", MixedBreed: true, Sex: SexMale, CurrentWeight: 12000, ActivityLevel: ActivityModerate}
+			if err := s.pets.Update(ctx, mixed); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.pets.Update(ctx, mixed); err != nil {
+				t.Errorf("updating with the same values returned %v", err)
+			}
+			got, err = s.pets.Get(ctx, created.ID)
+			if err != nil || got.BreedID != nil || !got.MixedBreed || got.BirthDate != nil || len(got.Breeds) != 0 {
+				t.Errorf("Get after Update = %+v, %v", got, err)
+			}
+			if err := s.pets.Update(ctx, Pet{ID: created.ID + 1, Name: "Ghost"}); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("Update(unknown) returned %v", err)
+			}
+
+			cross.Breeds = []BreedShare{{BreedID: poodle.ID, Share: 60}, {BreedID: breed.ID, Share: 40}}
+			if err := s.pets.Update(ctx, cross); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.breeds.Delete(ctx, breed.ID); err != nil {
+				t.Fatal(err)
+			}
+			got, err = s.pets.Get(ctx, created.ID)
+			if err != nil || got.BreedID != nil || len(got.Breeds) != 1 || got.Breeds[0].BreedID != poodle.ID {
+				t.Errorf("Get after deleting the breed = %+v, %v, want no breed_id and only the poodle share", got, err)
+			}
+
+			pets, err := s.pets.List(ctx)
+			if err != nil || len(pets) != 1 || pets[0].ID != created.ID {
+				t.Errorf("List = %+v, %v", pets, err)
+			}
+			if err := s.pets.Delete(ctx, created.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.pets.Delete(ctx, created.ID); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("Delete twice returned %v", err)
+			}
+			if _, err := s.pets.Get(ctx, created.ID); !errors.Is(err, ErrPetNotFound) {
+				t.Errorf("Get after Delete returned %v", err)
+			}
+		})
+	}
+}
+
+func TestProductStores(t *testing.T) {
+	newStores := map[string]func(t *testing.T) ProductStore{
+		"memory": func(t *testing.T) ProductStore { return NewMemoryProductStore() },
+		"sql": func(t *testing.T) ProductStore {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM products"); err != nil {
+				t.Fatal(err)
+			}
+			return NewSQLProductStore(db, backend)
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+			kibble, err := s.Create(ctx, Product{Name: "Puppy kibble", Kind: ProductKibble, KcalPerKg: 3800, Species: "dog", PetSizes: []string{"small", "medium"}, LifeStages: []LifeStage{LifeStageEarlyGrowth, LifeStageGrowth}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			wet, err := s.Create(ctx, Product{Name: "Cat pâté", Kind: ProductWet, KcalPerKg: 1000, Species: "cat"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Get(ctx, kibble.ID)
+			if err != nil || got.Name != "Puppy kibble" || got.Kind != ProductKibble || got.KcalPerKg != 3800 ||
+				len(got.PetSizes) != 2 || got.PetSizes[1] != "medium" || len(got.LifeStages) != 2 || got.LifeStages[0] != LifeStageEarlyGrowth {
+				t.Errorf("Get = %+v, %v", got, err)
+			}
+			got, err = s.Get(ctx, wet.ID)
+			if err != nil || got.PetSizes == nil || len(got.PetSizes) != 0 || got.LifeStages == nil || len(got.LifeStages) != 0 {
+				t.Errorf("Get = %+v, %v, want empty lists", got, err)
+			}
+
+			products, err := s.List(ctx, ProductFilter{Species: "cat"})
+			if err != nil || len(products) != 1 || products[0].ID != wet.ID {
+				t.Errorf("List(cat) = %+v, %v", products, err)
+			}
+			products, err = s.List(ctx, ProductFilter{})
+			if err != nil || len(products) != 2 || products[0].ID != kibble.ID {
+				t.Errorf("List = %+v, %v", products, err)
+			}
+
+			kibble.LifeStages = nil
+			if err := s.Update(ctx, kibble); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Update(ctx, kibble); err != nil {
+				t.Errorf("updating with the same values returned %v", err)
+			}
+			got, err = s.Get(ctx, kibble.ID)
+			if err != nil || len(got.LifeStages) != 0 {
+				t.Errorf("Get after Update = %+v, %v", got, err)
+			}
+			if err := s.Update(ctx, Product{ID: wet.ID + 1, Name: "Ghost"}); !errors.Is(err, ErrProductNotFound) {
+				t.Errorf("Update(unknown) returned %v", err)
+			}
+
+			if err := s.Delete(ctx, kibble.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Delete(ctx, kibble.ID); !errors.Is(err, ErrProductNotFound) {
+				t.Errorf("Delete twice returned %v", err)
+			}
+			if _, err := s.Get(ctx, kibble.ID); !errors.Is(err, ErrProductNotFound) {
+				t.Errorf("Get after Delete returned %v", err)
+			}
+		})
+	}
+}
+
+func TestGrowthCurveStores(t *testing.T) {
+	type stores struct {
+		curves GrowthCurveStore
+		breeds BreedStore
+		// foreignKey deletes the curve of a deleted breed
+		foreignKey bool
+	}
+	newStores := map[string]func(t *testing.T) stores{
+		"memory": func(t *testing.T) stores {
+			return stores{curves: NewMemoryGrowthCurveStore(), breeds: NewMemoryBreedStore(fixtureBreeds)}
+		},
+		"sql": func(t *testing.T) stores {
+			db, backend := newSQLTestDB(t)
+			if _, err := db.Exec("DELETE FROM growth_curves"); err != nil {
+				t.Fatal(err)
+			}
+			return stores{curves: NewSQLGrowthCurveStore(db, backend), breeds: NewSQLBreedStore(db, backend), foreignKey: true}
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			if _, err := s.curves.Get(ctx, "dog", "small"); !errors.Is(err, ErrGrowthCurveNotFound) {
+				t.Errorf("Get(unknown) returned %v", err)
+			}
+			small := GrowthCurve{Species: "dog", PetSize: "small", BirthShare: 0.05, AdultWeeks: 40, Spread: 0.1}
+			for _, curve := range []GrowthCurve{small, {Species: "cat", PetSize: "medium", BirthShare: 0.03, AdultWeeks: 52, Spread: 0.1}} {
+				if err := s.curves.Set(ctx, curve); err != nil {
+					t.Fatal(err)
+				}
+			}
+			small.AdultWeeks = 44
+			if err := s.curves.Set(ctx, small); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.curves.Get(ctx, "dog", "small")
+			if err != nil || got != small {
+				t.Errorf("Get = %+v, %v, want %+v", got, err, small)
+			}
+			curves, err := s.curves.List(ctx)
+			if err != nil || len(curves) != 2 || curves[0].Species != "cat" || curves[1] != small {
+				t.Errorf("List = %+v, %v", curves, err)
+			}
+
+			breed, err := s.breeds.Create(ctx, Breed{Name: "beagle", Species: "dog", PetSize: "medium", WeightMin: 12000, WeightMax: 11000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			override := GrowthCurve{BreedID: breed.ID, BirthShare: 0.04, AdultWeeks: 48, Spread: 0.12}
+			if err := s.curves.SetBreed(ctx, override); err != nil {
+				t.Fatal(err)
+			}
+			override.Spread = 0.08
+			if err := s.curves.SetBreed(ctx, override); err != nil {
+				t.Fatal(err)
+			}
+			got, err = s.curves.GetBreed(ctx, breed.ID)
+			if err != nil || got != override {
+				t.Errorf("GetBreed = %+v, %v, want %+v", got, err, override)
+			}
+			if err := s.curves.DeleteBreed(ctx, breed.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.curves.DeleteBreed(ctx, breed.ID); !errors.Is(err, ErrGrowthCurveNotFound) {
+				t.Errorf("DeleteBreed twice returned %v", err)
+			}
+
+			if s.foreignKey {
+				if err := s.curves.SetBreed(ctx, override); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.breeds.Delete(ctx, breed.ID); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := s.curves.GetBreed(ctx, breed.ID); !errors.Is(err, ErrGrowthCurveNotFound) {
+					t.Errorf("GetBreed after deleting the breed returned %v", err)
+				}
+			}
+		})
+	}
+}
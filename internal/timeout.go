@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// statusClientClosedRequest is logged for requests whose client went away before the answer, as nginx does
+const statusClientClosedRequest = 499
+
+// QueryTimeouts bounds the database calls made by each operation, such as "breeds.search"
+//
+// Operations missing from PerOperation get Default, a zero timeout leaves them bounded by the request context only
+type QueryTimeouts struct {
+	Default      time.Duration
+	PerOperation map[string]time.Duration
+}
+
+// For returns the timeout of operation
+func (q QueryTimeouts) For(operation string) time.Duration {
+	if timeout, ok := q.PerOperation[operation]; ok {
+		return timeout
+	}
+	return q.Default
+}
+
+// queryContext derives the context of the database calls of operation from the request context
+func (a *App) queryContext(r *http.Request, operation string) (context.Context, context.CancelFunc) {
+	timeout := a.QueryTimeouts.For(operation)
+	if timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// storeFailed answers an unexpected store error: 504 when the operation timed out, 503 when the database cannot
+// be reached and 500 otherwise, nothing when the client went away
+//
+// ctx is the context the failed call ran with, drivers do not all report its cancellation as such
+func (a *App) storeFailed(w http.ResponseWriter, r *http.Request, ctx context.Context, err error, msg string, fields ...interface{}) {
+	fields = append(fields, "err", err)
+	var netErr net.Error
+	switch {
+	case errors.Is(r.Context().Err(), context.Canceled):
+		a.log(r).Warn("Client went away: "+msg, fields...)
+		w.WriteHeader(statusClientClosedRequest)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		a.log(r).Error(msg, fields...)
+		writeProblem(w, http.StatusGatewayTimeout, "The database did not answer in time")
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		a.log(r).Error(msg, fields...)
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, http.StatusServiceUnavailable, "The database is unavailable")
+	default:
+		a.log(r).Error(msg, fields...)
+		writeProblem(w, http.StatusInternalServerError, msg)
+	}
+}
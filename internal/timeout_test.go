@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+// failingBreedStore answers List and Search with err, once ctx is done when block is set
+type failingBreedStore struct {
+	BreedStore
+	block bool
+	err   error
+}
+
+func (s failingBreedStore) List(ctx context.Context) ([]Breed, error) {
+	if s.block {
+		<-ctx.Done()
+	}
+	return nil, s.err
+}
+
+func (s failingBreedStore) Search(ctx context.Context, _ BreedFilter) ([]Breed, error) {
+	return s.List(ctx)
+}
+
+func TestQueryTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      failingBreedStore
+		cancel     bool
+		wantStatus int
+	}{
+		{name: "deadline exceeded", store: failingBreedStore{block: true, err: context.DeadlineExceeded}, wantStatus: http.StatusGatewayTimeout},
+		{name: "driver interrupted", store: failingBreedStore{block: true, err: errors.New("interrupted (9)")}, wantStatus: http.StatusGatewayTimeout},
+		{name: "bad connection", store: failingBreedStore{err: driver.ErrBadConn}, wantStatus: http.StatusServiceUnavailable},
+		{name: "other error", store: failingBreedStore{err: errors.New("syntax error")}, wantStatus: http.StatusInternalServerError},
+		{name: "client went away", store: failingBreedStore{err: context.Canceled}, cancel: true, wantStatus: statusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp(charmLog.New(io.Discard))
+			app.Store = tt.store
+			app.QueryTimeouts = QueryTimeouts{
+				Default:      time.Hour,
+				PerOperation: map[string]time.Duration{"breeds.list": 10 * time.Millisecond},
+			}
+			r := mux.NewRouter()
+			app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/breeds", nil).WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != statusClientClosedRequest && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("content type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestQueryTimeoutsFor(t *testing.T) {
+	timeouts := QueryTimeouts{Default: time.Second, PerOperation: map[string]time.Duration{"breeds.search": time.Minute}}
+	if got := timeouts.For("breeds.search"); got != time.Minute {
+		t.Errorf("For(breeds.search) = %s", got)
+	}
+	if got := timeouts.For("breeds.get"); got != time.Second {
+		t.Errorf("For(breeds.get) = %s", got)
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+)
+
+// routeVariablePattern drops the patterns of mux route variables, `{id:[0-9]+}` being `{id}` in the spec
+var routeVariablePattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/merge-patch+json", openapi3filter.JSONBodyDecoder)
+}
+
+// SpecValidator checks requests, and optionally responses, against OpenAPISpec
+type SpecValidator struct {
+	doc      *openapi3.T
+	basePath string
+	// ValidateResponses logs the responses which do not match the spec, to catch contract drift in development
+	ValidateResponses bool
+}
+
+// NewSpecValidator loads OpenAPISpec, whose first server URL is the prefix RegisterRoutes is mounted on
+func NewSpecValidator() (*SpecValidator, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	// openapi3 implements OpenAPI 3.0, the 3.1 keywords used by the spec are annotations
+	err = doc.Validate(context.Background(), openapi3.DisableExamplesValidation(), openapi3.AllowExtraSiblingFields("examples"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	v := &SpecValidator{doc: doc}
+	if len(doc.Servers) > 0 {
+		v.basePath = strings.TrimSuffix(doc.Servers[0].URL, "/")
+	}
+	return v, nil
+}
+
+// route returns the spec route matching the mux route of r, nil for routes the spec does not describe
+func (v *SpecValidator) route(r *http.Request) *routers.Route {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return nil
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	path := strings.TrimPrefix(routeVariablePattern.ReplaceAllString(template, "{$1}"), v.basePath)
+	item := v.doc.Paths.Find(path)
+	if item == nil {
+		return nil
+	}
+	operation := item.GetOperation(r.Method)
+	if operation == nil {
+		return nil
+	}
+	return &routers.Route{Spec: v.doc, Path: path, PathItem: item, Method: r.Method, Operation: operation}
+}
+
+// validate answers 400 to the requests whose parameters or body do not match the spec, the routes it does not
+// describe, such as CORS preflights, are let through
+func (a *App) validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := a.Validator.route(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		options := &openapi3filter.Options{
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+			SkipSettingDefaults:   true,
+			IncludeResponseStatus: true,
+		}
+		options.WithCustomSchemaErrorFunc(schemaErrorDetail)
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: mux.Vars(r),
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			a.log(r).Warn("Invalid request", "err", err)
+			writeProblem(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !a.Validator.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		err := openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.status,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(&rec.body),
+			Options:                options,
+		})
+		if err != nil {
+			a.log(r).Error("Response does not match the OpenAPI spec", "status", rec.status, "err", err)
+		}
+	})
+}
+
+// schemaErrorDetail describes a schema error without dumping the schema
+func schemaErrorDetail(err *openapi3.SchemaError) string {
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return fmt.Sprintf("%s: %s", strings.Join(pointer, "."), err.Reason)
+	}
+	return err.Reason
+}
+
+// bodyRecorder copies the response it writes through, for it to be validated once sent
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bodyRecorder) WriteHeader(status int) {
+	b.status = status
+	b.ResponseWriter.WriteHeader(status)
+}
+
+func (b *bodyRecorder) Write(p []byte) (int, error) {
+	b.body.Write(p)
+	return b.ResponseWriter.Write(p)
+}
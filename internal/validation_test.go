@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+func newValidatedTestRouter(t *testing.T, store BreedStore, logs *bytes.Buffer) *mux.Router {
+	t.Helper()
+	validator, err := NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	app := NewApp(charmLog.New(logs))
+	app.Store = store
+	app.Validator = validator
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	return r
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "search", method: http.MethodGet, path: "/v1/breeds/search?species=dog&weight=6000", wantStatus: http.StatusOK},
+		{name: "search invalid weight", method: http.MethodGet, path: "/v1/breeds/search?weight=six", wantStatus: http.StatusBadRequest},
+		{name: "search negative weight", method: http.MethodGet, path: "/v1/breeds/search?weight=-1", wantStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/v1/breeds/1", wantStatus: http.StatusOK},
+		{name: "get id 0", method: http.MethodGet, path: "/v1/breeds/0", wantStatus: http.StatusBadRequest},
+		{name: "get unknown", method: http.MethodGet, path: "/v1/breeds/42", wantStatus: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, path: "/v1/breeds", body: `{"name":"beagle","species":"dog","average_weight":12000}`, wantStatus: http.StatusCreated},
+		{name: "create without name", method: http.MethodPost, path: "/v1/breeds", body: `{"species":"dog","average_weight":12000}`, wantStatus: http.StatusBadRequest},
+		{name: "create string weight", method: http.MethodPost, path: "/v1/breeds", body: `{"name":"beagle","species":"dog","average_weight":"heavy"}`, wantStatus: http.StatusBadRequest},
+		{name: "update", method: http.MethodPut, path: "/v1/breeds/1", body: `{"name":"affen","species":"dog","average_weight":5000}`, wantStatus: http.StatusOK},
+		{name: "update invalid body", method: http.MethodPut, path: "/v1/breeds/1", body: `nope`, wantStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/v1/breeds/3", wantStatus: http.StatusNoContent},
+		{name: "list page", method: http.MethodGet, path: "/v1/breeds?limit=2&after_id=1", wantStatus: http.StatusOK},
+		{name: "list limit too high", method: http.MethodGet, path: "/v1/breeds?limit=5000", wantStatus: http.StatusBadRequest},
+		{name: "patch", method: http.MethodPatch, path: "/v1/breeds/2", body: `{"average_weight":40000}`, wantStatus: http.StatusOK},
+		{name: "patch merge-patch", method: http.MethodPatch, path: "/v1/breeds/2", contentType: "application/merge-patch+json", body: `{"name":"akita inu"}`, wantStatus: http.StatusOK},
+		{name: "patch nothing", method: http.MethodPatch, path: "/v1/breeds/2", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "patch unknown", method: http.MethodPatch, path: "/v1/breeds/42", body: `{"name":"akita inu"}`, wantStatus: http.StatusNotFound},
+		{name: "import", method: http.MethodPost, path: "/v1/breeds/import", contentType: "text/csv", body: "id,species,pet_size,name,male,female\n1,dog,medium,beagle,12000,11000\n", wantStatus: http.StatusCreated},
+		{name: "import JSON", method: http.MethodPost, path: "/v1/breeds/import", body: `{"name":"beagle"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, NewMemoryBreedStore(fixtureBreeds), &logs)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			} else if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("content type %q", rec.Header().Get("Content-Type"))
+			}
+			if strings.Contains(logs.String(), "Response does not match") {
+				t.Errorf("response flagged as not matching the spec: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestResponseValidation(t *testing.T) {
+	var logs bytes.Buffer
+	r := newValidatedTestRouter(t, failingBreedStore{err: context.Canceled}, &logs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/breeds", nil).WithContext(ctx))
+
+	if !strings.Contains(logs.String(), "Response does not match the OpenAPI spec") {
+		t.Errorf("an undocumented status should be logged, got %q", logs.String())
+	}
+}
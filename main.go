@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,20 +14,17 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/Asto-42/TechTestJaphy/database_actions"
 	"github.com/Asto-42/TechTestJaphy/internal"
-	"github.com/Asto-42/TechTestJaphy/tests"
-
-	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 const (
-	MysqlDSN   = "root:root@(mysql-test:3306)/?parseTime=true"
 	ApiPort    = "5000"
 	BreedsFile = "./breeds.csv"
 )
 
 func main() {
 	logger := charmLog.NewWithOptions(os.Stderr, charmLog.Options{
-		Formatter:       charmLog.TextFormatter,
+		Formatter:       logFormatter(),
 		ReportCaller:    true,
 		ReportTimestamp: true,
 		TimeFormat:      time.Kitchen,
@@ -33,39 +32,140 @@ func main() {
 		Level:           charmLog.DebugLevel,
 	})
 
-	db, err := sql.Open("mysql", MysqlDSN)
+	shutdownTracing := initTracing(logger)
+	err := run(logger)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if shutdownErr := shutdownTracing(ctx); shutdownErr != nil {
+		logger.Error(fmt.Sprintf("Failed to flush traces: %s", shutdownErr.Error()))
+	}
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("Failed to connect to MySQL: %s", err.Error()))
-		os.Exit(1)
+		logger.Fatal(err.Error())
+	}
+}
+
+// run serves the API until it fails, its errors are returned rather than exiting so that main flushes the traces
+//
+// Settings are read before the database is reached, their Fatal errors exit before any span is recorded
+func run(logger *charmLog.Logger) error {
+	storeKind := flag.String("store", "sql", "breeds store: `sql` (see DB_BACKEND) or `memory`")
+	flag.Parse()
+
+	app := internal.NewApp(logger)
+	app.AdminToken = os.Getenv("ADMIN_TOKEN")
+	app.Metrics = internal.NewMetrics()
+	app.RateLimiter = rateLimiterFromEnv(logger)
+	app.QueryTimeouts = queryTimeoutsFromEnv(logger)
+	app.SizeClasses = sizeClassesFromEnv(logger)
+	app.Validator = specValidatorFromEnv(logger)
+	cors := corsConfigFromEnv(logger)
+	security := securityConfigFromEnv(logger)
+
+	switch *storeKind {
+	case "memory":
+		records, err := database_actions.ReadBreedsFile(BreedsFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read breeds: %w", err)
+		}
+		app.Store = internal.NewMemoryBreedStore(database_actions.ClassifyBreeds(records, app.SizeClasses.Classify))
+		app.APIKeys = internal.NewMemoryAPIKeyStore()
+		app.Roles = internal.NewMemoryRoleStore()
+		app.Pets = internal.NewMemoryPetStore(app.Store)
+		app.Products = internal.NewMemoryProductStore()
+		app.Growth = internal.NewMemoryGrowthCurveStore()
+		logger.Info(fmt.Sprintf("Serving %d breeds from memory", len(records)))
+	case "sql":
+		db, backend, err := initDatabase(logger, app.Metrics, app.SizeClasses)
+		if db != nil {
+			defer db.Close()
+		}
+		if err != nil {
+			return err
+		}
+		app.Store = internal.NewSQLBreedStore(db, backend)
+		app.APIKeys = internal.NewSQLAPIKeyStore(db, backend)
+		app.Roles = internal.NewSQLRoleStore(db, backend)
+		app.Pets = internal.NewSQLPetStore(db, backend)
+		app.Products = internal.NewSQLProductStore(db, backend)
+		app.Growth = internal.NewSQLGrowthCurveStore(db, backend)
+	default:
+		return fmt.Errorf("Unknown store: %s", *storeKind)
+	}
+
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		logger.Warn("Authentication is disabled, the /v1 routes are anonymous")
+	} else {
+		app.Auth = &internal.Authenticator{
+			Keys:     app.APIKeys,
+			Roles:    app.Roles,
+			Issuer:   os.Getenv("JWT_ISSUER"),
+			Audience: os.Getenv("JWT_AUDIENCE"),
+		}
+		if jwksFile := os.Getenv("JWKS_FILE"); jwksFile != "" {
+			jwks, err := internal.LoadJWKS(jwksFile)
+			if err != nil {
+				return err
+			}
+			app.Auth.JWKS = jwks
+		}
 	}
-	defer db.Close()
 
-	_, err = db.Exec("CREATE DATABASE IF NOT EXISTS core;")
+	r := mux.NewRouter()
+	r.Use(internal.CaptureRoute)
+	r.Use(otelmux.Middleware(ServiceName))
+	r.Use(internal.SecurityHeaders(security))
+	v1 := r.PathPrefix("/v1").Subrouter()
+	internal.EnableCORS(v1, cors)
+	app.RegisterRoutes(v1)
+	app.RegisterAdminRoutes(r.PathPrefix("/admin").Subrouter())
+
+	internal.RegisterDocs(r)
+	r.Handle("/metrics", app.Metrics.Handler()).Methods(http.MethodGet)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods(http.MethodGet)
+
+	logger.Info(fmt.Sprintf("API port: %s", ApiPort))
+	logger.Info(fmt.Sprintf("Server is listening on http://127.0.0.1:%s", ApiPort))
+	err := http.ListenAndServe(
+		net.JoinHostPort("127.0.0.1", ApiPort),
+		app.LogRequests(r),
+	)
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("Failed to create database: %s", err.Error()))
-		os.Exit(1)
+		return fmt.Errorf("Failed to start server: %w", err)
 	}
-	logger.Info("Database `core` ensured to exist")
+	return nil
+}
 
-	MysqlDSNWithDB := "root:root@(mysql-test:3306)/core?parseTime=true"
-	db, err = sql.Open("mysql", MysqlDSNWithDB)
+// initDatabase connects to the DB_BACKEND database, migrates it and imports the breeds, sized by classes
+//
+// The pool is returned along with an import error, for the caller to close it
+func initDatabase(logger *charmLog.Logger, metrics *internal.Metrics, classes internal.SizeClasses) (*sql.DB, database_actions.Backend, error) {
+	backend, err := database_actions.ParseBackend(os.Getenv("DB_BACKEND"))
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("Failed to connect to `core` database: %s", err.Error()))
-		os.Exit(1)
+		return nil, "", err
 	}
-	defer db.Close()
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" {
+		dsn = backend.DefaultDSN()
+	}
+	logger.Info(fmt.Sprintf("Using %s backend", backend))
 
-	err = db.Ping()
+	db, err := database_actions.Connect(context.Background(), backend, dsn, poolConfigFromEnv(logger), retryConfigFromEnv(logger),
+		func(attempt int, delay time.Duration, err error) {
+			logger.Warn("Database not reachable yet", "attempt", attempt, "retry_in", delay.Round(time.Millisecond), "err", err)
+		})
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("Failed to ping database: %s", err.Error()))
-		os.Exit(1)
+		return nil, "", fmt.Errorf("Failed to connect to database: %w", err)
 	}
-	logger.Info("Connected to database `core`")
+	logger.Info("Connected to database")
+	metrics.RegisterDB(db, "core")
 
-	err = database_actions.InitMigrator(MysqlDSNWithDB)
+	err = database_actions.InitMigrator(db, backend)
 	if err != nil {
-		logger.Fatal(err.Error())
+		return db, "", err
 	}
+	metrics.RegisterMigrations()
 
 	msg, err := database_actions.RunMigrate("up", 0)
 	if err != nil {
@@ -74,47 +174,12 @@ func main() {
 		logger.Info(msg)
 	}
 
-	err = database_actions.ImportBreeds(db, BreedsFile)
+	stats, err := database_actions.ImportBreeds(context.Background(), db, backend, BreedsFile, classes.Classify)
+	metrics.ObserveImport(stats)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to import breeds: %s", err.Error()))
-		os.Exit(1)
+		return db, "", fmt.Errorf("Failed to import breeds: %w", err)
 	}
-	logger.Info("Breeds imported successfully")
-
-	app := internal.NewApp(logger)
-	app.DB = db
-
-	r := mux.NewRouter()
-	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
-
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	}).Methods(http.MethodGet)
-
-	go func() {
-		logger.Info(fmt.Sprintf("API port: %s", ApiPort))
-		logger.Info(fmt.Sprintf("Server is listening on http://127.0.0.1:%s", ApiPort))
-		err := http.ListenAndServe(
-			net.JoinHostPort("127.0.0.1", ApiPort),
-			r,
-		)
-		if err != nil {
-			logger.Fatal(fmt.Sprintf("Failed to start server: %s", err.Error()))
-		}
-	}()
-
-	go func() {
-		logger.Info("Waiting for server readiness...")
-		for i := 1; i <= 10; i++ {
-			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s/health", ApiPort))
-			if err == nil && resp.StatusCode == http.StatusOK {
-				logger.Info("Server is ready. Starting tests.")
-				tests.StartTests()
-				break
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
+	logger.Info(fmt.Sprintf("%d breeds imported successfully", stats.Imported))
 
-	select {}
+	return db, backend, nil
 }
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Asto-42/TechTestJaphy/database_actions"
+	"github.com/Asto-42/TechTestJaphy/internal"
+	charmLog "github.com/charmbracelet/log"
+	"github.com/gorilla/mux"
+)
+
+const breedsFile = "../breeds.csv"
+
+// baseURL targets a running deployment, an in-process API serving the memory store is started when empty
+var baseURL = flag.String("base-url", os.Getenv("BREEDS_API_URL"), "base URL of the API under test, e.g. http://127.0.0.1:50010")
+
+func startServer(t *testing.T) string {
+	t.Helper()
+	if *baseURL != "" {
+		return strings.TrimSuffix(*baseURL, "/")
+	}
+
+	records, err := database_actions.ReadBreedsFile(breedsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := internal.NewApp(charmLog.New(io.Discard))
+	app.Store = internal.NewMemoryBreedStore(records)
+	app.Validator, err = internal.NewSpecValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Validator.ValidateResponses = true
+	r := mux.NewRouter()
+	app.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestEndToEnd(t *testing.T) {
+	url := startServer(t)
+	checker := NewChecker(url + "/v1")
+	checker.Logf = t.Logf
+
+	if err := checker.WaitForServer(url); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("breeds match CSV", func(t *testing.T) {
+		if err := checker.CheckBreedsMatchCSV(breedsFile); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("read-only", func(t *testing.T) {
+		if err := checker.CheckReadOnly(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("CRUD round-trip", func(t *testing.T) {
+		t.Cleanup(func() {
+			if err := checker.Cleanup(); err != nil {
+				t.Error(err)
+			}
+		})
+		if err := checker.CheckCRUD(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -1,62 +1,104 @@
 package tests
 
 import (
+	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strconv"
-	"bytes"
 	"time"
+
+	"github.com/Asto-42/TechTestJaphy/client"
 )
 
-type Breed struct {
-	ID            int     `json:"id"`
-	Name          string  `json:"name"`
-	Species       string  `json:"species"`
-	AverageWeight float64 `json:"average_weight"`
+// Checker runs the breeds API contract checks against a running deployment, through the client package
+type Checker struct {
+	// API calls the deployment, its Header is added to every request, e.g. to authenticate
+	API *client.BreedsClient
+	// Logf reports the progress of the checks, nothing is reported when nil
+	Logf func(format string, args ...interface{})
+
+	// created holds the breeds created by the checks and not deleted yet, see Cleanup
+	created []int
+}
+
+// NewChecker returns a Checker for the API at apiURL, e.g. http://127.0.0.1:5000/v1
+func NewChecker(apiURL string) *Checker {
+	api := client.New(apiURL)
+	api.HTTPClient.Timeout = 10 * time.Second
+	return &Checker{API: api}
+}
+
+// Cleanup deletes the breeds left behind by checks which failed halfway
+func (c *Checker) Cleanup() error {
+	var errs []error
+	for _, id := range c.created {
+		c.logf("🧹 Suppression de la race de test %d", id)
+		if err := c.Delete(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Checker) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
 }
 
-func WaitForServer(apiURL string) {
+// WaitForServer polls the health endpoint at baseURL until it answers 200
+func (c *Checker) WaitForServer(baseURL string) error {
 	const maxRetries = 10
 	const retryDelay = time.Second
 
 	for i := 0; i < maxRetries; i++ {
-		resp, err := http.Get(fmt.Sprintf("%s/health", apiURL))
-		if err == nil && resp.StatusCode == http.StatusOK {
-			fmt.Println("✅ Serveur prêt.")
-			return
+		if c.healthy(baseURL) {
+			c.logf("✅ Serveur prêt.")
+			return nil
 		}
-		fmt.Printf("⏳ En attente du serveur... Tentative %d/%d\n", i+1, maxRetries)
+		c.logf("⏳ En attente du serveur... Tentative %d/%d", i+1, maxRetries)
 		time.Sleep(retryDelay)
 	}
 
-	fmt.Println("❌ Le serveur n'est pas prêt après plusieurs tentatives.")
-	os.Exit(1)
+	return fmt.Errorf("le serveur n'est pas prêt après %d tentatives", maxRetries)
 }
 
-func StartTests() {
-	fmt.Println("\n\n=== Début des tests de l'API Breeds ===")
+// healthy reports whether GET /health answers 200, the route is outside the API so the client does not cover it
+func (c *Checker) healthy(baseURL string) bool {
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/health", nil)
+	if err != nil {
+		return false
+	}
+	for key, values := range c.API.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	resp, err := c.API.HTTPClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
 
-	csvFile := "./breeds.csv"
-	apiURL := "http://127.0.0.1:5000/v1/breeds"
-	
-	fmt.Println("🔍 Lecture des données du fichier CSV...")
+// ReadExpectedBreeds returns the breeds of the CSV file as the API is expected to serve them
+func ReadExpectedBreeds(csvFile string) ([]client.Breed, error) {
 	file, err := os.Open(csvFile)
 	if err != nil {
-		fmt.Printf("❌ Erreur lors de l'ouverture du fichier CSV : %s\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("erreur lors de l'ouverture du fichier CSV : %w", err)
 	}
 	defer file.Close()
 
 	csvReader := csv.NewReader(file)
-	expectedBreeds := []Breed{}
+	expectedBreeds := []client.Breed{}
 	_, err = csvReader.Read()
 	if err != nil {
-		fmt.Printf("❌ Erreur lors de la lecture de l'en-tête CSV : %s\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("erreur lors de la lecture de l'en-tête CSV : %w", err)
 	}
 
 	for {
@@ -65,195 +107,213 @@ func StartTests() {
 			break
 		}
 		if err != nil {
-			fmt.Printf("❌ Erreur lors de la lecture du fichier CSV : %s\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("erreur lors de la lecture du fichier CSV : %w", err)
 		}
 
 		weightMin, _ := strconv.Atoi(record[4])
 		weightMax, _ := strconv.Atoi(record[5])
 		averageWeight := float64(weightMin+weightMax) / 2
 
-		expectedBreeds = append(expectedBreeds, Breed{
+		expectedBreeds = append(expectedBreeds, client.Breed{
 			Name:          record[3],
 			Species:       record[1],
 			AverageWeight: averageWeight,
 		})
 	}
-	fmt.Println("✅ Lecture du fichier CSV réussie.")
-	fmt.Println("🔍 Envoi de la requête à l'API...")
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		fmt.Printf("❌ Erreur lors de la requête à l'API : %s\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("❌ L'API a retourné un code HTTP inattendu : %d\n", resp.StatusCode)
-		os.Exit(1)
+	return expectedBreeds, nil
+}
+
+// CheckBreedsMatchCSV compares the breeds listed by the API, page by page, with the breeds of the CSV file, in order
+func (c *Checker) CheckBreedsMatchCSV(csvFile string) error {
+	c.logf("🔍 Lecture des données du fichier CSV...")
+	expectedBreeds, err := ReadExpectedBreeds(csvFile)
+	if err != nil {
+		return err
 	}
 
-	var apiBreeds []Breed
-	if err := json.NewDecoder(resp.Body).Decode(&apiBreeds); err != nil {
-		fmt.Printf("❌ Erreur lors du décodage JSON de la réponse API : %s\n", err)
-		os.Exit(1)
+	c.logf("🔍 Envoi de la requête à l'API...")
+	apiBreeds, err := c.list()
+	if err != nil {
+		return err
 	}
-	fmt.Println("🔍 Comparaison des données entre CSV et API...")
+	c.logf("🔍 Comparaison des données entre CSV et API...")
 	if len(expectedBreeds) != len(apiBreeds) {
-		fmt.Printf("❌ Nombre de races différentes. CSV : %d, API : %d\n", len(expectedBreeds), len(apiBreeds))
-		os.Exit(1)
+		return fmt.Errorf("nombre de races différentes. CSV : %d, API : %d", len(expectedBreeds), len(apiBreeds))
 	}
 
 	for i, expected := range expectedBreeds {
 		api := apiBreeds[i]
 		if expected.Name != api.Name || expected.Species != api.Species || expected.AverageWeight != api.AverageWeight {
-			fmt.Printf("❌ Mismatch à l'index %d : attendu %+v, reçu %+v\n", i, expected, api)
-			os.Exit(1)
+			return fmt.Errorf("mismatch à l'index %d : attendu %+v, reçu %+v", i, expected, api)
 		}
 	}
-	fmt.Println("✅ Toutes les données correspondent entre le CSV et l'API.")
-	fmt.Println("=== Tests terminés avec succès ===")
-	testOtherEndpoint(apiURL)
+	c.logf("✅ Toutes les données correspondent entre le CSV et l'API.")
+	return nil
 }
 
-func testOtherEndpoint(apiURL string) {
+// CheckReadOnly reads the first listed breed by id and finds it through a search on its species
+//
+// It never writes, so it can run against any deployment
+func (c *Checker) CheckReadOnly() error {
+	c.logf("🔍 Test de GET /v1/breeds...")
+	it := c.API.List(context.Background())
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return fmt.Errorf("erreur lors de la requête à l'API : %w", err)
+		}
+		return errors.New("l'API ne retourne aucune race")
+	}
+	first := it.Breed()
+
+	c.logf("🔍 Test de GET /v1/breeds/{id}...")
+	if err := c.Get(first.ID, first); err != nil {
+		return err
+	}
 
-	fmt.Printf("🔍 URL de base pour l'API : %s\n", apiURL)
+	c.logf("🔍 Test de GET /v1/breeds/search...")
+	found, err := c.API.Search(context.Background(), client.SearchFilter{Species: first.Species})
+	if err != nil {
+		return fmt.Errorf("erreur lors de la recherche : %w", err)
+	}
+	for _, breed := range found {
+		if breed.Species != first.Species {
+			return fmt.Errorf("la recherche par espèce %s a retourné %+v", first.Species, breed)
+		}
+		if breed.ID == first.ID {
+			c.logf("✅ Tests en lecture seule réussis.")
+			return nil
+		}
+	}
+	return fmt.Errorf("la recherche par espèce %s ne retourne pas la race %d", first.Species, first.ID)
+}
 
-	fmt.Println("🔍 Test de POST /v1/breeds...")
-	newBreed := Breed{
+// CheckCRUD creates a breed then reads, updates and deletes it
+func (c *Checker) CheckCRUD() error {
+	c.logf("🔍 Test de POST /v1/breeds...")
+	newBreed := client.Breed{
 		Name:          "Test Breed",
 		Species:       "Test Species",
 		AverageWeight: 15.0,
 	}
-	newBreedID := testPost(apiURL, newBreed)
-	
-	fmt.Println("🔍 Validation de la création avec GET...")
-	fmt.Printf("🔍 URL utilisée pour GET : %s/%d\n", apiURL, newBreedID)
-	testGet(apiURL, newBreedID, newBreed)
+	newBreedID, err := c.Post(newBreed)
+	if err != nil {
+		return err
+	}
+
+	c.logf("🔍 Validation de la création avec GET...")
+	if err := c.Get(newBreedID, newBreed); err != nil {
+		return err
+	}
 
-	fmt.Println("🔍 Test de PUT /v1/breeds/{id}...")
-	updatedBreed := Breed{
+	c.logf("🔍 Test de PUT /v1/breeds/{id}...")
+	updatedBreed := client.Breed{
 		Name:          "Updated Test Breed",
 		Species:       "Updated Test Species",
 		AverageWeight: 20.0,
 	}
-	testPut(apiURL, newBreedID, updatedBreed)
+	if err := c.Put(newBreedID, updatedBreed); err != nil {
+		return err
+	}
 
-	fmt.Println("🔍 Validation de la mise à jour avec GET...")
-	testGet(apiURL, newBreedID, updatedBreed)
+	c.logf("🔍 Validation de la mise à jour avec GET...")
+	if err := c.Get(newBreedID, updatedBreed); err != nil {
+		return err
+	}
 
-	fmt.Println("🔍 Test de DELETE /v1/breeds/{id}...")
-	testDelete(apiURL, newBreedID)
+	c.logf("🔍 Test de DELETE /v1/breeds/{id}...")
+	if err := c.Delete(newBreedID); err != nil {
+		return err
+	}
 
-	fmt.Println("🔍 Validation de la suppression avec GET...")
-	testGetDeleted(apiURL, newBreedID)
+	c.logf("🔍 Validation de la suppression avec GET...")
+	if err := c.GetDeleted(newBreedID); err != nil {
+		return err
+	}
 
-	fmt.Println("✅ Tous les tests CRUD ont réussi.")
+	c.logf("✅ Tous les tests CRUD ont réussi.")
+	return nil
 }
 
-func testPost(apiURL string, breed Breed) int {
-    body, _ := json.Marshal(breed)
-    resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(body))
-    if err != nil {
-        fmt.Printf("❌ Erreur lors de POST : %s\n", err)
-        os.Exit(1)
-    }
-    defer resp.Body.Close()
-    if resp.StatusCode != http.StatusCreated {
-        fmt.Printf("❌ POST a retourné un code inattendu : %d\n", resp.StatusCode)
-        os.Exit(1)
-    }
-    var createdBreed Breed
-    err = json.NewDecoder(resp.Body).Decode(&createdBreed)
-    if err != nil {
-        fmt.Printf("❌ Erreur lors du décodage de la réponse POST : %s\n", err)
-        os.Exit(1)
-    }
-    fmt.Printf("✅ POST réussi. ID créé : %d\n", createdBreed.ID)
-    return createdBreed.ID
+// list returns all the breeds, following the pages of the API
+func (c *Checker) list() ([]client.Breed, error) {
+	var breeds []client.Breed
+	it := c.API.List(context.Background())
+	for it.Next() {
+		breeds = append(breeds, it.Breed())
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("erreur lors de la requête à l'API : %w", err)
+	}
+	return breeds, nil
 }
 
-
-func testGet(apiURL string, id int, expected Breed) {
-	finalURL := fmt.Sprintf("%s/%d", apiURL, id)
-	fmt.Printf("🔍 Envoi de la requête GET à l'URL : %s\n", finalURL)
-
-	resp, err := http.Get(finalURL)
+// Post creates breed and returns its id
+func (c *Checker) Post(breed client.Breed) (int, error) {
+	created, err := c.API.Create(context.Background(), breed)
 	if err != nil {
-		fmt.Printf("❌ Erreur lors de GET : %s\n", err)
-		os.Exit(1)
+		return 0, fmt.Errorf("erreur lors de POST : %w", err)
 	}
-	defer resp.Body.Close()
+	c.created = append(c.created, created.ID)
+	c.logf("✅ POST réussi. ID créé : %d", created.ID)
+	return created.ID, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("❌ GET a retourné un code inattendu : %d\n", resp.StatusCode)
-		os.Exit(1)
+// Get checks that the breed id matches expected
+func (c *Checker) Get(id int, expected client.Breed) error {
+	breed, err := c.API.Get(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("erreur lors de GET : %w", err)
 	}
 
-	var breed Breed
-	json.NewDecoder(resp.Body).Decode(&breed)
-
 	if breed.Name != expected.Name || breed.Species != expected.Species || breed.AverageWeight != expected.AverageWeight {
-		fmt.Printf("❌ GET : Données incorrectes. Attendu %+v, reçu %+v\n", expected, breed)
-		os.Exit(1)
+		return fmt.Errorf("GET : données incorrectes. Attendu %+v, reçu %+v", expected, breed)
 	}
-	fmt.Println("✅ GET réussi.")
+	c.logf("✅ GET réussi.")
+	return nil
 }
 
-
-func testPut(apiURL string, id int, updated Breed) {
-	body, _ := json.Marshal(updated)
-	req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/%d", apiURL, id), bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("❌ Erreur lors de PUT : %s\n", err)
-		os.Exit(1)
+// Put replaces the breed id with updated
+func (c *Checker) Put(id int, updated client.Breed) error {
+	updated.ID = id
+	if err := c.API.Update(context.Background(), updated); err != nil {
+		return fmt.Errorf("erreur lors de PUT : %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("❌ PUT a retourné un code inattendu : %d\n", resp.StatusCode)
-		os.Exit(1)
+	c.logf("✅ PUT réussi.")
+	return nil
+}
+
+// Delete removes the breed id
+func (c *Checker) Delete(id int) error {
+	if err := c.API.Delete(context.Background(), id); err != nil {
+		return fmt.Errorf("erreur lors de DELETE : %w", err)
 	}
 
-	fmt.Println("✅ PUT réussi.")
+	c.forget(id)
+	c.logf("✅ DELETE réussi.")
+	return nil
 }
 
-func testDelete(apiURL string, id int) {
-	req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%d", apiURL, id), nil)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("❌ Erreur lors de DELETE : %s\n", err)
-		os.Exit(1)
+// GetDeleted checks that the breed id no longer exists
+func (c *Checker) GetDeleted(id int) error {
+	_, err := c.API.Get(context.Background(), id)
+	if err == nil {
+		return fmt.Errorf("GET après suppression a retourné la race %d", id)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		fmt.Printf("❌ DELETE a retourné un code inattendu : %d\n", resp.StatusCode)
-		os.Exit(1)
+	if !errors.Is(err, client.ErrNotFound) {
+		return fmt.Errorf("GET après suppression a retourné une erreur inattendue : %w", err)
 	}
 
-	fmt.Println("✅ DELETE réussi.")
+	c.logf("✅ Validation de la suppression réussie.")
+	return nil
 }
 
-func testGetDeleted(apiURL string, id int) {
-	resp, err := http.Get(fmt.Sprintf("%s/%d", apiURL, id))
-	if err != nil {
-		fmt.Printf("❌ Erreur lors de GET : %s\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNotFound {
-		fmt.Printf("❌ GET après suppression a retourné un code inattendu : %d\n", resp.StatusCode)
-		os.Exit(1)
+func (c *Checker) forget(id int) {
+	for i, created := range c.created {
+		if created == id {
+			c.created = append(c.created[:i], c.created[i+1:]...)
+			return
+		}
 	}
-
-	fmt.Println("✅ Validation de la suppression réussie.")
 }
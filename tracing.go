@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	charmLog "github.com/charmbracelet/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const ServiceName = "breeds-api"
+
+// initTracing installs the W3C trace context propagator and the tracer provider exporting to OTEL_TRACES_EXPORTER:
+// `otlp` (OTLP over HTTP, see OTEL_EXPORTER_OTLP_ENDPOINT), `stdout` or `none`, the default
+//
+// The returned function flushes the pending spans
+func initTracing(logger *charmLog.Logger) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName := envOr("OTEL_TRACES_EXPORTER", "none"); exporterName {
+	case "none":
+		return func(context.Context) error { return nil }
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background())
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		logger.Fatal(fmt.Sprintf("OTEL_TRACES_EXPORTER: unknown exporter %s", exporterName))
+	}
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create the trace exporter: %s", err.Error()))
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to describe the trace resource: %s", err.Error()))
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	logger.Info(fmt.Sprintf("Exporting traces to %s", os.Getenv("OTEL_TRACES_EXPORTER")))
+
+	return provider.Shutdown
+}